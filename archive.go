@@ -0,0 +1,933 @@
+package tanuki
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+)
+
+// Formats
+
+type archiveFormat string
+
+const (
+	formatZip   archiveFormat = "zip"
+	formatRar   archiveFormat = "rar"
+	format7z    archiveFormat = "7z"
+	formatTar   archiveFormat = "tar"
+	formatTarGz archiveFormat = "tar.gz"
+	formatPdf   archiveFormat = "pdf"
+	formatEpub  archiveFormat = "epub"
+	formatDir   archiveFormat = "folder"
+)
+
+// Some formats define the order of their pages, so
+// their pages shouldn't be sorted by name
+func (f archiveFormat) ordered() bool {
+	return f == formatPdf || f == formatEpub
+}
+
+type archiveType struct {
+	Format archiveFormat
+	Mime   string
+}
+
+// Entries are only parsed if their extension exists in
+// this map, the MIME is what we advertise to clients
+// when they download the archive
+var archiveTypes = map[string]archiveType{
+	".zip":    {formatZip, "application/zip"},
+	".cbz":    {formatZip, "application/zip"},
+	".rar":    {formatRar, "application/vnd.rar"},
+	".cbr":    {formatRar, "application/vnd.comicbook-rar"},
+	".7z":     {format7z, "application/x-7z-compressed"},
+	".cb7":    {format7z, "application/x-cb7"},
+	".tar":    {formatTar, "application/x-tar"},
+	".cbt":    {formatTar, "application/x-cbt"},
+	".tar.gz": {formatTarGz, "application/gzip"},
+	".tgz":    {formatTarGz, "application/gzip"},
+	".pdf":    {formatPdf, "application/pdf"},
+	".epub":   {formatEpub, "application/epub+zip"},
+}
+
+// Folders of images are zipped when they're downloaded
+var dirType = archiveType{formatDir, "application/zip"}
+
+func archiveTypeOf(path string) (archiveType, bool) {
+	t, found := archiveTypes[strings.ToLower(archiveExt(path))]
+	return t, found
+}
+
+// Compressed tarballs have a double extension, e.g. ".tar.gz",
+// so we can't rely on filepath.Ext to find the whole extension
+func archiveExt(path string) string {
+	ext := filepath.Ext(path)
+	if inner := filepath.Ext(strings.TrimSuffix(path, ext)); strings.EqualFold(inner, ".tar") {
+		return inner + ext
+	}
+	return ext
+}
+
+// Archive
+
+type archiveFile struct {
+	Name    string
+	NonUtf8 bool
+	// Only set by formats which can read a
+	// file directly from its offset
+	Offset int64
+	Size   int64
+	// The file's uncompressed size, if the format lists it
+	Bytes int64
+}
+
+type archive interface {
+	// Lists every regular file in the archive,
+	// directories should not be included
+	files() ([]archiveFile, error)
+	// Reads the contents of the page
+	read(p Page) ([]byte, error)
+	Close() error
+}
+
+// Formats which can stream a page, so only as much
+// of the page as is needed has to be read from it
+type pageOpener interface {
+	archive
+	open(p Page) (io.ReadCloser, error)
+}
+
+// Formats which can only be read sequentially, so every
+// file can be read in a single pass over the archive
+// rather than a pass for each file
+type fileWalker interface {
+	archive
+	walkFiles(fn func(name string, r io.Reader) error) error
+}
+
+// Some formats describe the entry they contain, the
+// metadata is available once the files are listed
+type metadataArchive interface {
+	archive
+	metadata() archiveMetadata
+}
+
+type archiveMetadata struct {
+	Title  string
+	Author string
+}
+
+// The cache is optional, and is only used by formats
+// which are expensive to read from repeatedly
+func openArchive(path string, cache *blockCache) (archive, error) {
+	if stat, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if stat.IsDir() {
+		return &dirArchive{path}, nil
+	}
+
+	t, found := archiveTypeOf(path)
+	if !found {
+		return nil, fmt.Errorf("unsupported archive: %s", filepath.Base(path))
+	}
+
+	switch t.Format {
+	case formatZip:
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		return &zipArchive{r}, nil
+	case formatRar:
+		return &rarArchive{path}, nil
+	case format7z:
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		r, err := sevenzip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		return &sevenZipArchive{r: r, path: path, stat: stat, cache: cache}, nil
+	case formatTar, formatTarGz:
+		return &tarArchive{path: path, compressed: t.Format == formatTarGz}, nil
+	case formatEpub:
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		return &epubArchive{zip: &zipArchive{r}}, nil
+	case formatPdf:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return &pdfArchive{f}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %s", t.Format)
+	}
+}
+
+// Zip
+
+type zipArchive struct {
+	r *zip.ReadCloser
+}
+
+func (a *zipArchive) files() ([]archiveFile, error) {
+	fs := make([]archiveFile, 0, len(a.r.File))
+	for _, f := range a.r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		name := f.Name
+		if f.NonUTF8 {
+			var err error
+			name, err = decodeCP437(f.Name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CP437 name for page %s: %w", f.FileInfo().Name(), err)
+			}
+		}
+		fs = append(fs, archiveFile{Name: name, NonUtf8: f.NonUTF8, Bytes: int64(f.UncompressedSize64)})
+	}
+	return fs, nil
+}
+
+func (a *zipArchive) read(p Page) ([]byte, error) {
+	f, err := a.open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+func (a *zipArchive) open(p Page) (io.ReadCloser, error) {
+	// If the path was originally non-UTF-8 encoded then we
+	// can't directly Open the path, since it doesn't exist
+	// under the UTF-8 name. Instead we need to do a page
+	// by page comparison in the ZIP file... blegh!
+	var f io.ReadCloser
+	var err error
+	if !p.NonUtf8 {
+		f, err = a.r.Open(p.Path)
+	} else {
+		for _, f2 := range a.r.File {
+			if !f2.NonUTF8 {
+				continue
+			}
+			var f2Name string
+			f2Name, err = decodeCP437(f2.Name)
+			if err != nil {
+				return nil, err
+			}
+			if p.Path == f2Name {
+				f, err = f2.Open()
+				goto FoundFile
+			}
+		}
+		return nil, fmt.Errorf("non-utf-8 page not found")
+	}
+FoundFile:
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (a *zipArchive) Close() error {
+	return a.r.Close()
+}
+
+// Rar
+
+// RAR archives can be solid, meaning a file can only be
+// decompressed after every file before it. To keep this
+// simple we always read the archive sequentially, which
+// works for both solid and non-solid archives
+type rarArchive struct {
+	path string
+}
+
+func (a *rarArchive) walk(fn func(h *rardecode.FileHeader, r io.Reader) (bool, error)) error {
+	r, err := rardecode.OpenReader(a.path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for {
+		h, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if h.IsDir {
+			continue
+		}
+
+		done, err := fn(h, r)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+func (a *rarArchive) files() ([]archiveFile, error) {
+	fs := make([]archiveFile, 0)
+	return fs, a.walk(func(h *rardecode.FileHeader, _ io.Reader) (bool, error) {
+		f := archiveFile{Name: h.Name}
+		if !h.UnKnownSize {
+			f.Bytes = h.UnPackedSize
+		}
+		fs = append(fs, f)
+		return false, nil
+	})
+}
+
+func (a *rarArchive) walkFiles(fn func(name string, r io.Reader) error) error {
+	return a.walk(func(h *rardecode.FileHeader, r io.Reader) (bool, error) {
+		return false, fn(h.Name, r)
+	})
+}
+
+func (a *rarArchive) read(p Page) ([]byte, error) {
+	var data []byte
+	err := a.walk(func(h *rardecode.FileHeader, r io.Reader) (bool, error) {
+		if h.Name != p.Path {
+			return false, nil
+		}
+		var err error
+		data, err = io.ReadAll(r)
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("page not found: %s", p.Path)
+	}
+	return data, nil
+}
+
+func (a *rarArchive) Close() error {
+	return nil
+}
+
+// 7z
+
+type sevenZipArchive struct {
+	r     *sevenzip.ReadCloser
+	path  string
+	stat  os.FileInfo
+	cache *blockCache
+}
+
+func (a *sevenZipArchive) files() ([]archiveFile, error) {
+	fs := make([]archiveFile, 0, len(a.r.File))
+	for _, f := range a.r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		fs = append(fs, archiveFile{Name: f.Name, Bytes: int64(f.UncompressedSize)})
+	}
+	return fs, nil
+}
+
+// Files are read in archive order, so each
+// stream is only decompressed once
+func (a *sevenZipArchive) walkFiles(fn func(name string, r io.Reader) error) error {
+	for _, f := range a.r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = fn(f.Name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *sevenZipArchive) read(p Page) ([]byte, error) {
+	var target *sevenzip.File
+	for _, f := range a.r.File {
+		if f.Name == p.Path {
+			target = f
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("page not found: %s", p.Path)
+	}
+	if a.cache == nil {
+		return readSevenZipFile(target)
+	}
+
+	// The archive's path, size and mod time are part of
+	// the key, so we never serve stale data if the archive
+	// is replaced on disk
+	key := fmt.Sprintf("%s:%d:%d:%d", a.path, a.stat.Size(),
+		a.stat.ModTime().UnixNano(), target.Stream)
+	if files, found := a.cache.get(key); found {
+		if data, found := files[p.Path]; found {
+			return data, nil
+		}
+	}
+
+	// Files within the same stream are stored sequentially,
+	// so reading them in archive order decompresses the
+	// whole block exactly once
+	files := make(map[string][]byte)
+	for _, f := range a.r.File {
+		if f.Stream != target.Stream || f.FileInfo().IsDir() {
+			continue
+		}
+		data, err := readSevenZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		files[f.Name] = data
+	}
+	a.cache.put(key, files)
+
+	return files[p.Path], nil
+}
+
+func (a *sevenZipArchive) Close() error {
+	return a.r.Close()
+}
+
+func readSevenZipFile(f *sevenzip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Tar
+
+type tarArchive struct {
+	path       string
+	compressed bool
+}
+
+func (a *tarArchive) walk(fn func(h *tar.Header, offset int64, r io.Reader) (bool, error)) error {
+	f, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if a.compressed {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// The tar reader doesn't buffer, so after reading
+		// the header the file is positioned at the start
+		// of the member's data. This is meaningless for
+		// compressed tarballs since the file is positioned
+		// wherever the decompressor has read up to
+		offset := int64(-1)
+		if !a.compressed {
+			offset, err = f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+		}
+
+		done, err := fn(h, offset, tr)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+func (a *tarArchive) files() ([]archiveFile, error) {
+	fs := make([]archiveFile, 0)
+	return fs, a.walk(func(h *tar.Header, offset int64, _ io.Reader) (bool, error) {
+		f := archiveFile{Name: h.Name, Bytes: h.Size}
+		if offset >= 0 {
+			f.Offset = offset
+			f.Size = h.Size
+		}
+		fs = append(fs, f)
+		return false, nil
+	})
+}
+
+func (a *tarArchive) read(p Page) ([]byte, error) {
+	// If we know where the page is then we can seek
+	// straight to it instead of reading the archive
+	if !a.compressed && p.Size > 0 {
+		f, err := os.Open(a.path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readPageAt(f, p)
+	}
+
+	var data []byte
+	err := a.walk(func(h *tar.Header, _ int64, r io.Reader) (bool, error) {
+		if h.Name != p.Path {
+			return false, nil
+		}
+		var err error
+		data, err = io.ReadAll(r)
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("page not found: %s", p.Path)
+	}
+	return data, nil
+}
+
+// Only pages whose location is known can be streamed
+func (a *tarArchive) open(p Page) (io.ReadCloser, error) {
+	if a.compressed || p.Size <= 0 {
+		data, err := a.read(p)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	f, err := os.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	return sectionReadCloser{io.NewSectionReader(f, p.Offset, p.Size), f}, nil
+}
+
+func (a *tarArchive) Close() error {
+	return nil
+}
+
+// Pdf
+
+// Each page of the PDF is treated as a file within the
+// archive, whose data is the page's embedded JPEG
+type pdfArchive struct {
+	f *os.File
+}
+
+func (a *pdfArchive) files() ([]archiveFile, error) {
+	stat, err := a.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r, err := newPdfReader(a.f, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+	return r.images()
+}
+
+func (a *pdfArchive) read(p Page) ([]byte, error) {
+	p, err := a.locate(p)
+	if err != nil {
+		return nil, err
+	}
+	return readPageAt(a.f, p)
+}
+
+func (a *pdfArchive) open(p Page) (io.ReadCloser, error) {
+	p, err := a.locate(p)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(io.NewSectionReader(a.f, p.Offset, p.Size)), nil
+}
+
+// The page's location is always recorded when the
+// PDF is parsed, but if it's missing we can still
+// find the page by parsing the PDF again
+func (a *pdfArchive) locate(p Page) (Page, error) {
+	if p.Size > 0 {
+		return p, nil
+	}
+	fs, err := a.files()
+	if err != nil {
+		return Page{}, err
+	}
+	for _, f := range fs {
+		if f.Name == p.Path {
+			p.Offset, p.Size = f.Offset, f.Size
+			return p, nil
+		}
+	}
+	return Page{}, fmt.Errorf("page not found: %s", p.Path)
+}
+
+func (a *pdfArchive) Close() error {
+	return a.f.Close()
+}
+
+// Epub
+
+// EPUBs are ZIP archives whose pages are XHTML documents,
+// we only support fixed-layout EPUBs where each document
+// in the spine displays a single image
+type epubArchive struct {
+	zip  *zipArchive
+	meta archiveMetadata
+}
+
+type epubContainer struct {
+	Rootfiles []struct {
+		Path string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Title    []string `xml:"metadata>title"`
+	Creator  []string `xml:"metadata>creator"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+func (a *epubArchive) unmarshal(name string, v any) error {
+	data, err := a.zip.read(Page{Path: name})
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
+
+func (a *epubArchive) files() ([]archiveFile, error) {
+	// The container tells us where the package document is,
+	// which describes the EPUB's metadata and reading order
+	var c epubContainer
+	if err := a.unmarshal("META-INF/container.xml", &c); err != nil {
+		return nil, fmt.Errorf("read container: %w", err)
+	}
+	if len(c.Rootfiles) == 0 {
+		return nil, fmt.Errorf("package document not found")
+	}
+	opfPath := c.Rootfiles[0].Path
+
+	var pkg epubPackage
+	if err := a.unmarshal(opfPath, &pkg); err != nil {
+		return nil, fmt.Errorf("read package document: %w", err)
+	}
+	if len(pkg.Title) > 0 {
+		a.meta.Title = strings.TrimSpace(pkg.Title[0])
+	}
+	if len(pkg.Creator) > 0 {
+		a.meta.Author = strings.TrimSpace(pkg.Creator[0])
+	}
+
+	type item struct{ href, mediaType string }
+	manifest := make(map[string]item, len(pkg.Manifest))
+	for _, m := range pkg.Manifest {
+		manifest[m.ID] = item{resolveEpubHref(opfPath, m.Href), m.MediaType}
+	}
+
+	fs := make([]archiveFile, 0, len(pkg.Spine))
+	for _, ref := range pkg.Spine {
+		it, found := manifest[ref.IDRef]
+		if !found {
+			return nil, fmt.Errorf("spine item not in manifest: %s", ref.IDRef)
+		}
+		if strings.HasPrefix(it.mediaType, "image/") {
+			fs = append(fs, archiveFile{Name: it.href})
+			continue
+		}
+
+		img, err := a.documentImage(it.href)
+		if err != nil {
+			return nil, fmt.Errorf("read spine item %s: %w", it.href, err)
+		}
+		// Documents without images, e.g. a table of
+		// contents, are not pages of the manga
+		if img != "" {
+			fs = append(fs, archiveFile{Name: img})
+		}
+	}
+
+	sizes := make(map[string]int64, len(a.zip.r.File))
+	for _, f := range a.zip.r.File {
+		sizes[f.Name] = int64(f.UncompressedSize64)
+	}
+	for i := range fs {
+		fs[i].Bytes = sizes[fs[i].Name]
+	}
+	return fs, nil
+}
+
+// Returns the path of the first image displayed
+// by the document, or an empty string if it has
+// no images
+func (a *epubArchive) documentImage(path string) (string, error) {
+	data, err := a.zip.read(Page{Path: path})
+	if err != nil {
+		return "", err
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range el.Attr {
+			// Images are either HTML <img src=""> or
+			// SVG <image href=""> (or xlink:href)
+			if (el.Name.Local == "img" && attr.Name.Local == "src") ||
+				(el.Name.Local == "image" && attr.Name.Local == "href") {
+				return resolveEpubHref(path, attr.Value), nil
+			}
+		}
+	}
+}
+
+func (a *epubArchive) read(p Page) ([]byte, error) {
+	return a.zip.read(p)
+}
+
+func (a *epubArchive) open(p Page) (io.ReadCloser, error) {
+	return a.zip.open(p)
+}
+
+func (a *epubArchive) metadata() archiveMetadata {
+	return a.meta
+}
+
+func (a *epubArchive) Close() error {
+	return a.zip.Close()
+}
+
+// Hrefs are URL-encoded and relative to
+// the document which references them
+func resolveEpubHref(doc, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Join(path.Dir(doc), href)
+}
+
+// Folder
+
+// Folders of loose images are read straight from disk
+type dirArchive struct {
+	path string
+}
+
+func (a *dirArchive) files() ([]archiveFile, error) {
+	items, err := os.ReadDir(a.path)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := make([]archiveFile, 0, len(items))
+	for _, item := range items {
+		// Folders commonly contain other files, e.g. notes
+		// from the scanlators, so only images and metadata
+		// are listed
+		if !item.Type().IsRegular() || !(isImage(item.Name()) || isComicInfo(item.Name())) {
+			continue
+		}
+		info, err := item.Info()
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, archiveFile{Name: item.Name(), Bytes: info.Size()})
+	}
+	return fs, nil
+}
+
+func (a *dirArchive) read(p Page) ([]byte, error) {
+	return os.ReadFile(filepath.Join(a.path, filepath.Base(p.Path)))
+}
+
+func (a *dirArchive) open(p Page) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(a.path, filepath.Base(p.Path)))
+}
+
+func (a *dirArchive) Close() error {
+	return nil
+}
+
+// Writes the pages of a folder as a ZIP archive, the
+// images are already compressed so they're only stored
+func writeDirZip(w io.Writer, dir string, ps Pages) error {
+	zw := zip.NewWriter(w)
+	for _, p := range ps {
+		f, err := os.Open(filepath.Join(dir, filepath.Base(p.Path)))
+		if err != nil {
+			return err
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		fh, err := zip.FileInfoHeader(stat)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		fh.Method = zip.Store
+		zf, err := zw.CreateHeader(fh)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		_, err = io.Copy(zf, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// Helpers
+
+type sectionReadCloser struct {
+	*io.SectionReader
+	io.Closer
+}
+
+func readPageAt(r io.ReaderAt, p Page) ([]byte, error) {
+	data := make([]byte, p.Size)
+	if _, err := r.ReadAt(data, p.Offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Block Cache
+
+// Solid archives compress many files into a single block,
+// so reading any one page means decompressing every page
+// stored before it. We keep recently decompressed blocks
+// around so that streaming an entry page by page stays fast
+type blockCache struct {
+	mu     sync.Mutex
+	max    int64
+	size   int64
+	blocks *list.List // Most recently used blocks are at the front
+	keys   map[string]*list.Element
+}
+
+type cachedBlock struct {
+	key   string
+	size  int64
+	files map[string][]byte
+}
+
+func newBlockCache(max int64) *blockCache {
+	return &blockCache{
+		max:    max,
+		blocks: list.New(),
+		keys:   make(map[string]*list.Element),
+	}
+}
+
+func (c *blockCache) get(key string) (map[string][]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.keys[key]
+	if !found {
+		return nil, false
+	}
+	c.blocks.MoveToFront(el)
+	return el.Value.(*cachedBlock).files, true
+}
+
+func (c *blockCache) put(key string, files map[string][]byte) {
+	b := &cachedBlock{key: key, files: files}
+	for _, data := range files {
+		b.size += int64(len(data))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Blocks which can never fit are not cached
+	if b.size > c.max {
+		return
+	}
+	if el, found := c.keys[key]; found {
+		c.remove(el)
+	}
+	c.keys[key] = c.blocks.PushFront(b)
+	c.size += b.size
+
+	for c.size > c.max {
+		c.remove(c.blocks.Back())
+	}
+}
+
+func (c *blockCache) remove(el *list.Element) {
+	b := c.blocks.Remove(el).(*cachedBlock)
+	delete(c.keys, b.key)
+	c.size -= b.size
+}
@@ -0,0 +1,84 @@
+package tanuki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveExt(t *testing.T) {
+	tests := map[string]string{
+		"a/b.zip":        ".zip",
+		"a/b.v1.cbz":     ".cbz",
+		"a/b.tar":        ".tar",
+		"a/b.tar.gz":     ".tar.gz",
+		"a/b.TAR.GZ":     ".TAR.GZ",
+		"a/b.tgz":        ".tgz",
+		"a/b.tar/c.gz":   ".gz",
+		"a/b":            "",
+		"a/Vol. 1.5.cbr": ".cbr",
+	}
+	for path, ext := range tests {
+		require.Equal(t, ext, archiveExt(path), path)
+	}
+}
+
+func TestTarArchive_Read(t *testing.T) {
+	e, err := ParseEntry("tests/lib-cbt/Amano/Amano Megumi wa Suki Darake! v01.cbt")
+	require.NoError(t, err)
+	a, err := openArchive(e.Archive, nil)
+	require.NoError(t, err)
+	defer a.Close()
+
+	// Reading the page from its offset should give
+	// the same data as walking through the archive
+	for _, p := range e.Pages {
+		seeked, err := a.read(p)
+		require.NoError(t, err)
+		require.Len(t, seeked, int(p.Size))
+
+		p.Offset, p.Size = 0, 0
+		walked, err := a.read(p)
+		require.NoError(t, err)
+		require.Equal(t, walked, seeked)
+	}
+}
+
+func TestBlockCache(t *testing.T) {
+	c := newBlockCache(10)
+
+	t.Run("missing block", func(t *testing.T) {
+		_, found := c.get("a")
+		require.False(t, found)
+	})
+
+	t.Run("cached block", func(t *testing.T) {
+		c.put("a", map[string][]byte{"1": []byte("aaaa")})
+		files, found := c.get("a")
+		require.True(t, found)
+		require.Equal(t, map[string][]byte{"1": []byte("aaaa")}, files)
+		require.EqualValues(t, 4, c.size)
+	})
+
+	t.Run("oversized block is not cached", func(t *testing.T) {
+		c.put("b", map[string][]byte{"1": []byte("bbbbbbbbbbb")})
+		_, found := c.get("b")
+		require.False(t, found)
+		require.EqualValues(t, 4, c.size)
+	})
+
+	t.Run("least recently used block is evicted", func(t *testing.T) {
+		c.put("c", map[string][]byte{"1": []byte("cccc")})
+		_, found := c.get("a") // "c" is now the least recently used
+		require.True(t, found)
+		c.put("d", map[string][]byte{"1": []byte("dddd")})
+
+		_, found = c.get("c")
+		require.False(t, found)
+		_, found = c.get("a")
+		require.True(t, found)
+		_, found = c.get("d")
+		require.True(t, found)
+		require.EqualValues(t, 8, c.size)
+	})
+}
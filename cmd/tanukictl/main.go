@@ -9,6 +9,7 @@ import (
 	"net"
 	"net/rpc"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -58,8 +59,16 @@ func run(host, port string, printVersion bool) error {
 		return scanLibrary(rpc)
 	case "dump":
 		return dumpStore(rpc)
+	case "schema":
+		return schemaVersion(rpc)
 	case "user":
 		return modifyUser(rpc)
+	case "series":
+		return modifySeries(rpc)
+	case "entry":
+		return modifyEntry(rpc)
+	case "duplicate":
+		return resolveDuplicate(rpc)
 	default:
 		slog.Error("Invalid command", slog.String("command", flag.Arg(0)))
 		flagUsage()
@@ -78,11 +87,24 @@ func flagUsage() {
 	fmt.Fprintf(out, "\n")
 	fmt.Fprintf(out, "Commands:\n")
 	fmt.Fprintf(out, "  scan                                  Scan the library\n")
+	fmt.Fprintf(out, "  scan -follow                          Scan the library in the background and follow its progress\n")
+	fmt.Fprintf(out, "  scan -status [-follow]                Show the status of the running scan, or the last one\n")
+	fmt.Fprintf(out, "  scan -cancel                          Cancel the running scan\n")
+	fmt.Fprintf(out, "  scan <path|sid> [-follow]             Rescan a single series, the rest of the catalog is left alone\n")
 	fmt.Fprintf(out, "  dump                                  Dump the store's state\n")
+	fmt.Fprintf(out, "  schema                                Show the store's schema version\n")
 	fmt.Fprintf(out, "  user add <name>                       Add a new user with the password provided via stdin\n")
 	fmt.Fprintf(out, "  user delete <name>                    Delete an existing user\n")
 	fmt.Fprintf(out, "  user edit name <old-name> <new-name>  Change a user's name\n")
 	fmt.Fprintf(out, "  user edit pass <name>                 Change a user's password provided via stdin\n")
+	fmt.Fprintf(out, "  series edit <sid> <field> <value>     Override a series' metadata, the value can be empty\n")
+	fmt.Fprintf(out, "  series reset <sid> <field>            Remove a series' override\n")
+	fmt.Fprintf(out, "  series tag add <sid> <tag>            Add a tag to a series\n")
+	fmt.Fprintf(out, "  series tag remove <sid> <tag>         Remove a tag from a series, even if it's in the series' metadata\n")
+	fmt.Fprintf(out, "  entry edit <sid> <eid> <field> <val>  Override an entry's metadata, the value can be empty\n")
+	fmt.Fprintf(out, "  entry reset <sid> <eid> <field>       Remove an entry's override\n")
+	fmt.Fprintf(out, "  duplicate keep <sid> [eid]            Keep every duplicate, the rest are identified by their path\n")
+	fmt.Fprintf(out, "  duplicate prefer <path> <sid> [eid]   Keep the duplicate at the path and ignore the rest\n")
 	fmt.Fprintf(out, "\n")
 	fmt.Fprintf(out, "  $ tanukictl -port 5000 scan\n")
 	fmt.Fprintf(out, "  $ tanukictl -port 5000 dump\n")
@@ -90,23 +112,206 @@ func flagUsage() {
 	fmt.Fprintf(out, "    // We connect to a tanuki instance listening on a\n")
 	fmt.Fprintf(out, "    // standard host but a non-standard port (5000)\n")
 	fmt.Fprintf(out, "\n")
+	fmt.Fprintf(out, "  $ tanukictl scan -follow\n")
+	fmt.Fprintf(out, "    // Scan the library and show its progress, stopping\n")
+	fmt.Fprintf(out, "    // with Ctrl+C leaves the scan running, follow it\n")
+	fmt.Fprintf(out, "    // again with scan -status -follow\n")
+	fmt.Fprintf(out, "\n")
+	fmt.Fprintf(out, "  $ tanukictl scan \"/library/Akira/Volume 03.zip\"\n")
+	fmt.Fprintf(out, "    // Rescan the Akira series once a new volume's been\n")
+	fmt.Fprintf(out, "    // downloaded to it, the series' SID works as well\n")
+	fmt.Fprintf(out, "\n")
 	fmt.Fprintf(out, "  $ tanukictl user edit name old-name new-name\n")
 	fmt.Fprintf(out, "  $ echo \"new-password\" | tanukictl user edit pass new-name\n")
 	fmt.Fprintf(out, "    // Edit a user's name, then their password\n")
+	fmt.Fprintf(out, "\n")
+	fmt.Fprintf(out, "  $ tanukictl series edit <sid> sort-title \"Boys, 20th Century\"\n")
+	fmt.Fprintf(out, "  $ tanukictl entry edit <sid> <eid> cover 2\n")
+	fmt.Fprintf(out, "    // Sort a series by a different title, then use an\n")
+	fmt.Fprintf(out, "    // entry's third page as its cover. The fields are\n")
+	fmt.Fprintf(out, "    // title, author, summary, sort-title and cover, only\n")
+	fmt.Fprintf(out, "    // entries have covers\n")
+	fmt.Fprintf(out, "\n")
+	fmt.Fprintf(out, "  $ tanukictl series tag add <sid> \"Slice of Life\"\n")
+	fmt.Fprintf(out, "  $ tanukictl series tag remove <sid> Comedy\n")
+	fmt.Fprintf(out, "    // Tag a series as slice of life, then untag it\n")
+	fmt.Fprintf(out, "    // as a comedy. Edits persist across scans\n")
+	fmt.Fprintf(out, "\n")
+	fmt.Fprintf(out, "  $ tanukictl duplicate prefer \"/library/Akira/Season 2/v01.zip\" <sid> <eid>\n")
+	fmt.Fprintf(out, "  $ tanukictl duplicate keep <sid> <eid>\n")
+	fmt.Fprintf(out, "    // Scans list the duplicates they find. Prefer the\n")
+	fmt.Fprintf(out, "    // second season's copy of an Akira volume over the\n")
+	fmt.Fprintf(out, "    // first's, then keep both of another pair of entries\n")
 }
 
 func scanLibrary(api *rpc.Client) error {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	follow := fs.Bool("follow", false, "Scan in the background and follow its progress")
+	status := fs.Bool("status", false, "Show the status of the running scan, or the last one")
+	cancel := fs.Bool("cancel", false, "Cancel the running scan")
+	fs.Parse(flag.Args()[1:])
+
+	// Parsing stops at the series, so flags which follow it,
+	// e.g. scan <path> -follow, are parsed separately
+	var target string
+	if fs.NArg() > 0 {
+		target = fs.Arg(0)
+		fs.Parse(fs.Args()[1:])
+	}
+
+	switch {
+	case *cancel:
+		if err := api.Call("Server.CancelScan", struct{}{}, &struct{}{}); err != nil {
+			return fmt.Errorf("cancel scan: %w", err)
+		}
+		fmt.Println("Cancelled scan")
+		return nil
+	case *status:
+		job := new(tanuki.ScanJob)
+		if err := api.Call("Server.ScanStatus", struct{}{}, job); err != nil {
+			return fmt.Errorf("get scan status: %w", err)
+		}
+		if *follow && job.Running {
+			return followScan(api, job.ID)
+		}
+		printScanJob(job)
+		return nil
+	case target != "":
+		return scanSeries(api, target, *follow)
+	case *follow:
+		job := new(tanuki.ScanJob)
+		if err := api.Call("Server.StartScan", struct{}{}, job); err != nil {
+			return fmt.Errorf("start scan: %w", err)
+		}
+		return followScan(api, job.ID)
+	}
+
 	start := time.Now()
-	if err := api.Call("Server.Scan", struct{}{}, &struct{}{}); err != nil {
+	result := new(tanuki.ScanResult)
+	if err := api.Call("Server.Scan", struct{}{}, result); err != nil {
 		// Even if it's only a partial failure, we don't
 		// attempt to disambiguate it, so it looks like
 		// a total failure
 		return fmt.Errorf("scan library: %w", err)
 	}
 	fmt.Printf("Scan complete in %s\n", time.Since(start).Round(time.Millisecond))
+	printScanResult(result)
 	return nil
 }
 
+// The target is either a series' SID or a path in its folder,
+// paths are made absolute since tanuki's working directory
+// may not be ours
+func scanSeries(api *rpc.Client, target string, follow bool) error {
+	if _, err := os.Stat(target); err == nil {
+		if abs, err := filepath.Abs(target); err == nil {
+			target = abs
+		}
+	}
+
+	if follow {
+		job := new(tanuki.ScanJob)
+		if err := api.Call("Server.StartScanSeries", target, job); err != nil {
+			return fmt.Errorf("start series scan: %w", err)
+		}
+		return followScan(api, job.ID)
+	}
+
+	start := time.Now()
+	result := new(tanuki.ScanResult)
+	if err := api.Call("Server.ScanSeries", target, result); err != nil {
+		return fmt.Errorf("scan series: %w", err)
+	}
+	fmt.Printf("Scan complete in %s\n", time.Since(start).Round(time.Millisecond))
+	printScanResult(result)
+	return nil
+}
+
+// Progress is rendered on a single line which is redrawn,
+// errors are printed above it as they're found
+func followScan(api *rpc.Client, id int) error {
+	t := time.NewTicker(500 * time.Millisecond)
+	defer t.Stop()
+
+	printed := 0
+	for {
+		job := new(tanuki.ScanJob)
+		if err := api.Call("Server.ScanStatus", struct{}{}, job); err != nil {
+			return fmt.Errorf("get scan status: %w", err)
+		}
+		if job.ID != id {
+			return fmt.Errorf("scan %d was replaced by scan %d", id, job.ID)
+		}
+
+		fmt.Print("\r\033[K")
+		for _, e := range job.Errors[printed:] {
+			fmt.Printf("Error: %s\n", e)
+		}
+		printed = len(job.Errors)
+
+		if !job.Running {
+			printScanJob(job)
+			return nil
+		}
+		fmt.Printf("Scanning: %d/%d series, %d/%d entries", job.Series, job.TotalSeries, job.Entries, job.TotalEntries)
+		if job.ETA > 0 {
+			fmt.Printf(", %s left", job.ETA)
+		}
+		if job.Current != "" {
+			fmt.Printf(" (%s)", filepath.Base(job.Current))
+		}
+		<-t.C
+	}
+}
+
+func printScanJob(job *tanuki.ScanJob) {
+	if job.Running {
+		fmt.Printf("Scan %d (%s) running for %s\n", job.ID, job.Trigger, time.Since(job.Started).Round(time.Second))
+		fmt.Printf("Series: %d/%d\n", job.Series, job.TotalSeries)
+		fmt.Printf("Entries: %d/%d\n", job.Entries, job.TotalEntries)
+		if job.ETA > 0 {
+			fmt.Printf("Time left: %s\n", job.ETA)
+		}
+		if job.Current != "" {
+			fmt.Printf("Current: %s\n", job.Current)
+		}
+		for _, e := range job.Errors {
+			fmt.Printf("Error: %s\n", e)
+		}
+		return
+	}
+
+	took := job.Finished.Sub(job.Started).Round(time.Millisecond)
+	if job.Err != "" {
+		fmt.Printf("Scan %d (%s) failed after %s: %s\n", job.ID, job.Trigger, took, job.Err)
+	} else {
+		fmt.Printf("Scan %d (%s) complete in %s\n", job.ID, job.Trigger, took)
+	}
+	printScanResult(&job.Result)
+}
+
+func printScanResult(result *tanuki.ScanResult) {
+	c := result.Changes
+	fmt.Printf("Entries: %d added, %d changed, %d removed, %d unchanged\n", c.Added, c.Changed, c.Removed, c.Unchanged)
+
+	for _, d := range result.Duplicates {
+		status := "unresolved"
+		if d.Prefer != "" {
+			status = "prefer " + d.Prefer
+		} else if d.Resolved {
+			status = "keep all"
+		}
+		if d.EID == "" {
+			fmt.Printf("Duplicate series %s (%s)\n", d.SID, status)
+		} else {
+			fmt.Printf("Duplicate entry %s %s (%s)\n", d.SID, d.EID, status)
+		}
+		for _, p := range d.Paths {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+}
+
 func dumpStore(api *rpc.Client) error {
 	output := new(string)
 	if err := api.Call("Server.Dump", struct{}{}, output); err != nil {
@@ -116,6 +321,115 @@ func dumpStore(api *rpc.Client) error {
 	return nil
 }
 
+func schemaVersion(api *rpc.Client) error {
+	version := new(int)
+	if err := api.Call("Server.SchemaVersion", struct{}{}, version); err != nil {
+		return fmt.Errorf("get schema version: %w", err)
+	}
+	fmt.Printf("Schema version %d\n", *version)
+	return nil
+}
+
+func modifySeries(api *rpc.Client) error {
+	switch flag.Arg(1) {
+	case "edit", "reset":
+		req := tanuki.EditSeriesRequest{
+			SID:    flag.Arg(2),
+			Field:  flag.Arg(3),
+			Value:  flag.Arg(4),
+			Remove: flag.Arg(1) == "reset",
+		}
+		// Values can be empty, so they have to be given
+		if !req.Remove && flag.NArg() < 5 {
+			slog.Error("Missing value", slog.String("field", req.Field))
+			flagUsage()
+			return nil
+		}
+		if err := api.Call("Server.EditSeries", req, &struct{}{}); err != nil {
+			return fmt.Errorf("edit series: %w", err)
+		}
+		fmt.Println("Edited series")
+	case "tag":
+		req := tanuki.EditSeriesTagRequest{
+			SID: flag.Arg(3),
+			Tag: flag.Arg(4),
+		}
+		switch flag.Arg(2) {
+		case "add":
+		case "remove":
+			req.Remove = true
+		default:
+			slog.Error("Invalid command", slog.String("command", flag.Arg(2)))
+			flagUsage()
+			return nil
+		}
+		if err := api.Call("Server.EditSeriesTag", req, &struct{}{}); err != nil {
+			return fmt.Errorf("edit series tag: %w", err)
+		}
+		fmt.Println("Edited series tag")
+	default:
+		slog.Error("Invalid command", slog.String("command", flag.Arg(1)))
+		flagUsage()
+	}
+
+	return nil
+}
+
+func modifyEntry(api *rpc.Client) error {
+	switch flag.Arg(1) {
+	case "edit", "reset":
+		req := tanuki.EditEntryRequest{
+			SID:    flag.Arg(2),
+			EID:    flag.Arg(3),
+			Field:  flag.Arg(4),
+			Value:  flag.Arg(5),
+			Remove: flag.Arg(1) == "reset",
+		}
+		if !req.Remove && flag.NArg() < 6 {
+			slog.Error("Missing value", slog.String("field", req.Field))
+			flagUsage()
+			return nil
+		}
+		if err := api.Call("Server.EditEntry", req, &struct{}{}); err != nil {
+			return fmt.Errorf("edit entry: %w", err)
+		}
+		fmt.Println("Edited entry")
+	default:
+		slog.Error("Invalid command", slog.String("command", flag.Arg(1)))
+		flagUsage()
+	}
+
+	return nil
+}
+
+func resolveDuplicate(api *rpc.Client) error {
+	var req tanuki.ResolveDuplicateRequest
+	switch flag.Arg(1) {
+	case "keep":
+		req.SID = flag.Arg(2)
+		req.EID = flag.Arg(3)
+	case "prefer":
+		req.Prefer = flag.Arg(2)
+		req.SID = flag.Arg(3)
+		req.EID = flag.Arg(4)
+		if req.Prefer == "" {
+			slog.Error("Missing preferred path")
+			flagUsage()
+			return nil
+		}
+	default:
+		slog.Error("Invalid command", slog.String("command", flag.Arg(1)))
+		flagUsage()
+		return nil
+	}
+
+	if err := api.Call("Server.ResolveDuplicate", req, &struct{}{}); err != nil {
+		return fmt.Errorf("resolve duplicate: %w", err)
+	}
+	fmt.Println("Resolved duplicate")
+	return nil
+}
+
 func modifyUser(api *rpc.Client) error {
 	stat, err := os.Stdin.Stat()
 	if err != nil {
@@ -0,0 +1,276 @@
+package tanuki
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Comic Info
+
+const comicInfoFilename = "ComicInfo.xml"
+
+func isComicInfo(name string) bool {
+	return strings.EqualFold(path.Base(name), comicInfoFilename)
+}
+
+// ComicInfo is the metadata format created by ComicRack,
+// archives store it alongside their pages
+type ComicInfo struct {
+	Series      string `xml:"Series" json:",omitempty"`
+	Number      string `xml:"Number" json:",omitempty"`
+	Volume      int    `xml:"Volume" json:",omitempty"`
+	Title       string `xml:"Title" json:",omitempty"`
+	Summary     string `xml:"Summary" json:",omitempty"`
+	Writer      string `xml:"Writer" json:",omitempty"`
+	Penciller   string `xml:"Penciller" json:",omitempty"`
+	Letterer    string `xml:"Letterer" json:",omitempty"`
+	Translator  string `xml:"Translator" json:",omitempty"`
+	Publisher   string `xml:"Publisher" json:",omitempty"`
+	Year        int    `xml:"Year" json:",omitempty"`
+	LanguageISO string `xml:"LanguageISO" json:",omitempty"`
+	AgeRating   string `xml:"AgeRating" json:",omitempty"`
+	Manga       string `xml:"Manga" json:",omitempty"` // One of Unknown, No, Yes, YesAndRightToLeft
+	Genre       string `xml:"Genre" json:",omitempty"` // Comma separated
+
+	Pages []ComicInfoPage `xml:"Pages>Page" json:",omitempty"`
+}
+
+type ComicInfoPage struct {
+	Image int    `xml:"Image,attr"` // Index of the page
+	Type  string `xml:"Type,attr,omitempty" json:",omitempty"`
+}
+
+// Numbers are parsed leniently, a malformed number is
+// left unset instead of rejecting the whole document
+func (ci *ComicInfo) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type comicInfo ComicInfo
+	var v struct {
+		comicInfo
+		Volume string `xml:"Volume"`
+		Year   string `xml:"Year"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*ci = ComicInfo(v.comicInfo)
+	ci.Volume = lenientInt(v.Volume)
+	ci.Year = lenientInt(v.Year)
+	return nil
+}
+
+func (p *ComicInfoPage) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type comicInfoPage ComicInfoPage
+	var v struct {
+		comicInfoPage
+		Image string `xml:"Image,attr"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*p = ComicInfoPage(v.comicInfoPage)
+	// Pages without a valid index don't refer to any page
+	image, err := strconv.Atoi(strings.TrimSpace(v.Image))
+	if err != nil {
+		image = -1
+	}
+	p.Image = image
+	return nil
+}
+
+func lenientInt(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func parseComicInfo(data []byte) (ComicInfo, error) {
+	var ci ComicInfo
+	if err := xml.Unmarshal(data, &ci); err != nil {
+		return ComicInfo{}, err
+	}
+
+	// Unknown values are treated as though they're unset
+	if strings.EqualFold(ci.AgeRating, "Unknown") {
+		ci.AgeRating = ""
+	}
+	if strings.EqualFold(ci.Manga, "Unknown") {
+		ci.Manga = ""
+	}
+	if ci.Year < 0 {
+		ci.Year = 0
+	}
+	return ci, nil
+}
+
+func (ci ComicInfo) IsManga() bool {
+	return strings.HasPrefix(ci.Manga, "Yes")
+}
+
+func (ci ComicInfo) RightToLeft() bool {
+	return ci.Manga == "YesAndRightToLeft"
+}
+
+// Describes the issue the metadata is for, e.g. "Akira #3: Tetsuo",
+// the entry's title usually comes from its filename instead
+func (ci ComicInfo) heading() string {
+	h := ci.Series
+	if ci.Number != "" {
+		h = strings.TrimSpace(h + " #" + ci.Number)
+	}
+	if ci.Title != "" && h != "" {
+		h += ": " + ci.Title
+	} else if ci.Title != "" {
+		h = ci.Title
+	}
+	return h
+}
+
+func (ci ComicInfo) Genres() []string {
+	return splitList(ci.Genre)
+}
+
+// Returns the index of the page marked as the front cover
+func (ci ComicInfo) FrontCover() (int, bool) {
+	for _, p := range ci.Pages {
+		if p.Type == "FrontCover" && p.Image >= 0 {
+			return p.Image, true
+		}
+	}
+	return 0, false
+}
+
+func (ci ComicInfo) Value() (driver.Value, error) {
+	return json.Marshal(ci)
+}
+
+func (ci *ComicInfo) Scan(src any) error {
+	return scanJSON(src, ci)
+}
+
+// Series Info
+
+// Series metadata is collected from the metadata
+// of its entries and any sidecar file, it only holds
+// comparable fields since series are used as map keys
+type SeriesInfo struct {
+	Publisher   string `json:",omitempty"`
+	Year        int    `json:",omitempty"` // Year of the earliest entry
+	LanguageISO string `json:",omitempty"`
+	AgeRating   string `json:",omitempty"`
+	Manga       string `json:",omitempty"`
+	Genre       string `json:",omitempty"` // Comma separated
+
+	// Creators other than writers, comma separated
+	Artist     string `json:",omitempty"`
+	Translator string `json:",omitempty"`
+	Letterer   string `json:",omitempty"`
+
+	// Only set by series.json
+	Name        string `json:",omitempty"` // Full name, the folder's name is the title
+	Description string `json:",omitempty"`
+	Status      string `json:",omitempty"`
+	ComicID     string `json:",omitempty"`
+}
+
+func newSeriesInfo(entries []Entry) SeriesInfo {
+	var si SeriesInfo
+	var genres []string
+	seen := make(map[string]struct{})
+	for _, e := range entries {
+		ci := e.Info
+		if si.Publisher == "" {
+			si.Publisher = ci.Publisher
+		}
+		if ci.Year != 0 && (si.Year == 0 || ci.Year < si.Year) {
+			si.Year = ci.Year
+		}
+		if si.LanguageISO == "" {
+			si.LanguageISO = ci.LanguageISO
+		}
+		if si.AgeRating == "" {
+			si.AgeRating = ci.AgeRating
+		}
+		if si.Manga == "" {
+			si.Manga = ci.Manga
+		}
+		for _, g := range ci.Genres() {
+			if _, found := seen[strings.ToLower(g)]; !found {
+				seen[strings.ToLower(g)] = struct{}{}
+				genres = append(genres, g)
+			}
+		}
+		si.Artist = mergeList(si.Artist, ci.Penciller)
+		si.Translator = mergeList(si.Translator, ci.Translator)
+		si.Letterer = mergeList(si.Letterer, ci.Letterer)
+	}
+	si.Genre = strings.Join(genres, ", ")
+	return si
+}
+
+func (si SeriesInfo) IsManga() bool {
+	return strings.HasPrefix(si.Manga, "Yes")
+}
+
+func (si SeriesInfo) RightToLeft() bool {
+	return si.Manga == "YesAndRightToLeft"
+}
+
+func (si SeriesInfo) Genres() []string {
+	return splitList(si.Genre)
+}
+
+func (si SeriesInfo) Value() (driver.Value, error) {
+	return json.Marshal(si)
+}
+
+func (si *SeriesInfo) Scan(src any) error {
+	return scanJSON(src, si)
+}
+
+// Helpers
+
+// Lists, like genres or creators, are comma separated
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Appends the items of b which aren't already in a,
+// items are compared case-insensitively
+func mergeList(a, b string) string {
+	items := splitList(a)
+	seen := make(map[string]struct{})
+	for _, item := range items {
+		seen[strings.ToLower(item)] = struct{}{}
+	}
+	for _, item := range splitList(b) {
+		if _, found := seen[strings.ToLower(item)]; !found {
+			seen[strings.ToLower(item)] = struct{}{}
+			items = append(items, item)
+		}
+	}
+	return strings.Join(items, ", ")
+}
+
+func scanJSON(src any, v any) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, v)
+	case string:
+		return json.Unmarshal([]byte(src), v)
+	default:
+		return fmt.Errorf("incompatible type")
+	}
+}
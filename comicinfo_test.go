@@ -0,0 +1,87 @@
+package tanuki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseComicInfo(t *testing.T) {
+	t.Run("unknown values", func(t *testing.T) {
+		ci, err := parseComicInfo([]byte(`<ComicInfo><AgeRating>Unknown</AgeRating><Manga>Unknown</Manga><Year>-1</Year></ComicInfo>`))
+		require.NoError(t, err)
+		require.Equal(t, ComicInfo{}, ci)
+	})
+
+	t.Run("manga", func(t *testing.T) {
+		for _, tc := range []struct {
+			manga       string
+			isManga     bool
+			rightToLeft bool
+		}{
+			{"", false, false},
+			{"No", false, false},
+			{"Yes", true, false},
+			{"YesAndRightToLeft", true, true},
+		} {
+			ci := ComicInfo{Manga: tc.manga}
+			require.Equal(t, tc.isManga, ci.IsManga())
+			require.Equal(t, tc.rightToLeft, ci.RightToLeft())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := parseComicInfo([]byte(`<ComicInfo><Year>2001</Year>`))
+		require.Error(t, err)
+	})
+
+	t.Run("invalid numbers", func(t *testing.T) {
+		// The rest of the metadata is still read
+		ci, err := parseComicInfo([]byte(`<ComicInfo><Title>a</Title><Volume>1.5</Volume><Year>abc</Year>` +
+			`<Pages><Page Image="b" Type="FrontCover"/></Pages></ComicInfo>`))
+		require.NoError(t, err)
+		require.Equal(t, ComicInfo{Title: "a", Pages: []ComicInfoPage{{Image: -1, Type: "FrontCover"}}}, ci)
+		_, found := ci.FrontCover()
+		require.False(t, found)
+	})
+}
+
+func TestNewSeriesInfo(t *testing.T) {
+	si := newSeriesInfo([]Entry{
+		{Info: ComicInfo{Year: 2002, Genre: "Action, Drama"}},
+		{Info: ComicInfo{Year: 2001, Publisher: "a", Genre: "drama,Comedy"}},
+		{Info: ComicInfo{Publisher: "b", LanguageISO: "en"}},
+	})
+	require.Equal(t, SeriesInfo{
+		Publisher:   "a",
+		Year:        2001,
+		LanguageISO: "en",
+		Genre:       "Action, Drama, Comedy",
+	}, si)
+	require.Equal(t, []string{"Action", "Drama", "Comedy"}, si.Genres())
+}
+
+func TestComicInfo_FrontCover(t *testing.T) {
+	ci, err := parseComicInfo([]byte(`<ComicInfo><Pages><Page Image="0" Type="InnerCover"/><Page Image="2" Type="FrontCover"/></Pages></ComicInfo>`))
+	require.NoError(t, err)
+	i, found := ci.FrontCover()
+	require.True(t, found)
+	require.Equal(t, 2, i)
+
+	_, found = ComicInfo{}.FrontCover()
+	require.False(t, found)
+}
+
+func TestMergeList(t *testing.T) {
+	require.Equal(t, "a, b", mergeList("a", "b"))
+	require.Equal(t, "a, b, c", mergeList("a,b", "B, c"))
+	require.Equal(t, "b", mergeList("", " b ,"))
+}
+
+func TestComicInfo_Heading(t *testing.T) {
+	require.Empty(t, ComicInfo{}.heading())
+	require.Equal(t, "Akira #3: Tetsuo", ComicInfo{Series: "Akira", Number: "3", Title: "Tetsuo"}.heading())
+	require.Equal(t, "Akira", ComicInfo{Series: "Akira"}.heading())
+	require.Equal(t, "#3", ComicInfo{Number: "3"}.heading())
+	require.Equal(t, "Tetsuo", ComicInfo{Title: "Tetsuo"}.heading())
+}
@@ -0,0 +1,90 @@
+package tanuki
+
+import (
+	"bytes"
+	"image"
+	"log/slog"
+	"math"
+)
+
+// Covers
+
+// Only the first few pages are considered when
+// looking for a cover, since decoding is slow
+const maxCoverCandidates = 3
+
+// Pages are near-blank if their brightness barely varies
+const blankStdDev = 10.0
+
+// Near-blank pages compress very well, so pages which take more
+// bytes per pixel than this are used without being decoded
+const blankMaxBytesPerPixel = 0.1
+
+// Chooses which page is the entry's cover. ComicInfo can mark
+// a page as the front cover, otherwise the first page which
+// isn't near-blank is used, e.g. to skip over credits pages
+// which are mostly white
+func chooseCover(a archive, pages Pages, info ComicInfo) int {
+	if i, found := info.FrontCover(); found && i >= 0 && i < len(pages) {
+		return i
+	}
+
+	for i := range min(len(pages), maxCoverCandidates) {
+		if !mayBeBlank(pages[i]) {
+			return i
+		}
+		data, err := a.read(pages[i])
+		if err != nil {
+			slog.Debug("Could not read cover candidate", slog.String("page", pages[i].Path), slog.Any("err", err))
+			continue
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			slog.Debug("Could not decode cover candidate", slog.String("page", pages[i].Path), slog.Any("err", err))
+			continue
+		}
+		if !isNearBlank(img) {
+			return i
+		}
+	}
+	return 0
+}
+
+// Uses the page's size and dimensions, which are measured
+// before the cover's chosen, pages which haven't been
+// measured have to be decoded
+func mayBeBlank(p Page) bool {
+	pixels := p.Width * p.Height
+	if pixels <= 0 || p.Bytes <= 0 {
+		return true
+	}
+	return float64(p.Bytes)/float64(pixels) <= blankMaxBytesPerPixel
+}
+
+// Samples the image's luminance on a grid and
+// checks whether it has a low standard deviation
+func isNearBlank(img image.Image) bool {
+	const samples = 64
+
+	b := img.Bounds()
+	if b.Empty() {
+		return true
+	}
+	stepX := max(b.Dx()/samples, 1)
+	stepY := max(b.Dy()/samples, 1)
+
+	var n, sum, sumSq float64
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			l := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) / 257
+			n++
+			sum += l
+			sumSq += l * l
+		}
+	}
+
+	mean := sum / n
+	variance := max(sumSq/n-mean*mean, 0)
+	return math.Sqrt(variance) < blankStdDev
+}
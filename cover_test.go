@@ -0,0 +1,93 @@
+package tanuki
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memArchive map[string][]byte
+
+func (a memArchive) files() ([]archiveFile, error) { return nil, nil }
+func (a memArchive) read(p Page) ([]byte, error)   { return a[p.Path], nil }
+func (a memArchive) Close() error                  { return nil }
+
+func blankImage() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 100, 100))
+	for i := range img.Pix {
+		img.Pix[i] = 250
+	}
+	return img
+}
+
+func stripedImage() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 100, 100))
+	for y := range 100 {
+		for x := range 100 {
+			if (x/10)%2 == 0 {
+				img.SetGray(x, y, color.Gray{Y: 20})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 230})
+			}
+		}
+	}
+	return img
+}
+
+func mustEncodePNG(t *testing.T, img image.Image) []byte {
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, png.Encode(buf, img))
+	return buf.Bytes()
+}
+
+func TestIsNearBlank(t *testing.T) {
+	require.True(t, isNearBlank(blankImage()))
+	require.False(t, isNearBlank(stripedImage()))
+	require.True(t, isNearBlank(image.NewGray(image.Rectangle{})))
+}
+
+func TestChooseCover(t *testing.T) {
+	a := memArchive{
+		"blank.png":   mustEncodePNG(t, blankImage()),
+		"striped.png": mustEncodePNG(t, stripedImage()),
+	}
+	pages := Pages{
+		{Path: "blank.png", Mime: "image/png"},
+		{Path: "striped.png", Mime: "image/png"},
+		{Path: "blank.png", Mime: "image/png"},
+	}
+
+	t.Run("skips blank pages", func(t *testing.T) {
+		require.Equal(t, 1, chooseCover(a, pages, ComicInfo{}))
+	})
+
+	t.Run("front cover", func(t *testing.T) {
+		info := ComicInfo{Pages: []ComicInfoPage{{Image: 2, Type: "FrontCover"}}}
+		require.Equal(t, 2, chooseCover(a, pages, info))
+	})
+
+	t.Run("front cover out of range", func(t *testing.T) {
+		info := ComicInfo{Pages: []ComicInfoPage{{Image: 10, Type: "FrontCover"}}}
+		require.Equal(t, 1, chooseCover(a, pages, info))
+	})
+
+	t.Run("detailed first page", func(t *testing.T) {
+		// The page isn't decoded, so it doesn't
+		// matter that it isn't in the archive
+		detailed := Page{Path: "missing.jpg", Mime: "image/jpeg", Width: 100, Height: 100, Bytes: 5000}
+		require.Equal(t, 0, chooseCover(a, Pages{detailed, pages[1]}, ComicInfo{}))
+
+		// Measured pages which compress well are still decoded
+		blank := pages[0]
+		blank.Width, blank.Height, blank.Bytes = 100, 100, int64(len(a["blank.png"]))
+		require.Equal(t, 1, chooseCover(a, Pages{blank, pages[1]}, ComicInfo{}))
+	})
+
+	t.Run("every page blank", func(t *testing.T) {
+		require.Equal(t, 0, chooseCover(a, Pages{pages[0], pages[2]}, ComicInfo{}))
+	})
+}
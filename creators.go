@@ -0,0 +1,274 @@
+package tanuki
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maruel/natural"
+)
+
+// Credits
+
+type Role string
+
+const (
+	RoleWriter     Role = "writer"
+	RoleArtist     Role = "artist"
+	RoleTranslator Role = "translator"
+	RoleLetterer   Role = "letterer"
+)
+
+// Roles in the order they're credited
+var roles = []Role{RoleWriter, RoleArtist, RoleTranslator, RoleLetterer}
+
+// A credit names a creator and their role in a series or entry,
+// the names of each role are stored as comma separated lists.
+// Writers are the series' or entry's author
+type Credit struct {
+	Name string
+	Role Role
+}
+
+func newCredits(writers, artists, translators, letterers string) []Credit {
+	var cs []Credit
+	for i, names := range []string{writers, artists, translators, letterers} {
+		for _, name := range splitList(names) {
+			cs = append(cs, Credit{Name: name, Role: roles[i]})
+		}
+	}
+	return cs
+}
+
+func (e Entry) Credits() []Credit {
+	return newCredits(e.Author, e.Info.Penciller, e.Info.Translator, e.Info.Letterer)
+}
+
+func (s Series) Credits() []Credit {
+	return newCredits(s.Author, s.Info.Artist, s.Info.Translator, s.Info.Letterer)
+}
+
+// Author File
+
+const authorFilename = "author.txt"
+
+var authorLineRegex = regexp.MustCompile(`(?i)^(.+?)\s*\((writer|artist|translator|letterer)\)$`)
+
+// Each line of author.txt names a creator of the series, optionally
+// followed by their role in brackets, e.g. "Nekoguchi (artist)".
+// Creators without a role are writers. The names are returned as
+// comma separated lists for each role
+func parseAuthorFile(data string) map[Role]string {
+	m := make(map[Role]string)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		role := RoleWriter
+		if match := authorLineRegex.FindStringSubmatch(line); match != nil {
+			line = match[1]
+			role = Role(strings.ToLower(match[2]))
+		}
+		m[role] = mergeList(m[role], line)
+	}
+	return m
+}
+
+// The author file takes precedence over the entries'
+// metadata, but only for the roles which it credits
+func (s *Series) applyAuthorFile(credits map[Role]string) {
+	if v, found := credits[RoleWriter]; found {
+		s.Author = v
+	}
+	if v, found := credits[RoleArtist]; found {
+		s.Info.Artist = v
+	}
+	if v, found := credits[RoleTranslator]; found {
+		s.Info.Translator = v
+	}
+	if v, found := credits[RoleLetterer]; found {
+		s.Info.Letterer = v
+	}
+}
+
+// Creators
+
+// Creators are credited in series and entries, like tags they
+// are case-insensitive so their ID is the hash of the lowercase
+// name, and they keep the first name they were seen with
+type Creator struct {
+	CID     string
+	Name    string
+	Roles   []Role    // Roles the creator is credited with
+	Count   int       // Number of series they're credited in
+	ModTime time.Time // Latest mod time of their series
+}
+
+func creatorID(name string) string {
+	return Sha256(strings.ToLower(name))
+}
+
+func (s *Store) addCreator(tx *sqlx.Tx, name string) (string, error) {
+	cid := creatorID(name)
+	_, err := tx.Exec(`INSERT INTO creators (cid, name) VALUES (?, ?) ON CONFLICT (cid) DO NOTHING`, cid, name)
+	return cid, err
+}
+
+// Credits are made with any overrides applied, since
+// overrides can change the series' or entry's author
+func (s *Store) setSeriesCredits(tx *sqlx.Tx, sr Series) error {
+	ovs, err := s.getOverrides(tx, sr.SID)
+	if err != nil {
+		return err
+	}
+	ovs[""].applySeries(&sr)
+
+	if _, err := tx.Exec(`DELETE FROM series_creators WHERE sid = ?`, sr.SID); err != nil {
+		return err
+	}
+	for i, c := range sr.Credits() {
+		cid, err := s.addCreator(tx, c.Name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO series_creators (sid, cid, role, position) VALUES (?, ?, ?, ?)
+			              ON CONFLICT DO NOTHING`, sr.SID, cid, c.Role, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) setEntryCredits(tx *sqlx.Tx, e Entry) error {
+	ovs, err := s.getOverrides(tx, e.SID)
+	if err != nil {
+		return err
+	}
+	ovs[e.EID].applyEntry(&e)
+
+	if _, err := tx.Exec(`DELETE FROM entry_creators WHERE sid = ? AND eid = ?`, e.SID, e.EID); err != nil {
+		return err
+	}
+	for i, c := range e.Credits() {
+		cid, err := s.addCreator(tx, c.Name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO entry_creators (sid, eid, cid, role, position) VALUES (?, ?, ?, ?, ?)
+			              ON CONFLICT DO NOTHING`, e.SID, e.EID, cid, c.Role, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) deleteUnusedCreators(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`DELETE FROM creators WHERE cid NOT IN (SELECT cid FROM series_creators)
+		                                      AND cid NOT IN (SELECT cid FROM entry_creators)`)
+	return err
+}
+
+// Creators are browsable by the series they're credited in,
+// which includes the series where they're only credited in
+// some of its entries
+func (s *Store) GetCreators() ([]Creator, error) {
+	type row struct {
+		CID     string
+		Name    string
+		Role    Role
+		SID     string
+		ModTime time.Time
+	}
+	var seriesRows, entryRows []row
+	err := s.pool.Select(&seriesRows, `SELECT c.cid, c.name, sc.role, s.sid, s.mod_time FROM creators c
+		                               JOIN series_creators sc ON c.cid = sc.cid
+		                               JOIN series s ON sc.sid = s.sid
+		                               WHERE s.missing = 0`)
+	if err != nil {
+		return nil, err
+	}
+	err = s.pool.Select(&entryRows, `SELECT c.cid, c.name, ec.role, s.sid, s.mod_time FROM creators c
+		                             JOIN entry_creators ec ON c.cid = ec.cid
+		                             JOIN series s ON ec.sid = s.sid
+		                             WHERE s.missing = 0`)
+	if err != nil {
+		return nil, err
+	}
+
+	var creators []Creator
+	index := make(map[string]int)
+	credited := make(map[string]map[Role]struct{})
+	series := make(map[string]map[string]struct{})
+	for _, r := range append(seriesRows, entryRows...) {
+		i, found := index[r.CID]
+		if !found {
+			i = len(creators)
+			index[r.CID] = i
+			creators = append(creators, Creator{CID: r.CID, Name: r.Name})
+			credited[r.CID] = make(map[Role]struct{})
+			series[r.CID] = make(map[string]struct{})
+		}
+		credited[r.CID][r.Role] = struct{}{}
+		series[r.CID][r.SID] = struct{}{}
+		if r.ModTime.After(creators[i].ModTime) {
+			creators[i].ModTime = r.ModTime
+		}
+	}
+	for i, c := range creators {
+		for _, role := range roles {
+			if _, found := credited[c.CID][role]; found {
+				creators[i].Roles = append(creators[i].Roles, role)
+			}
+		}
+		creators[i].Count = len(series[c.CID])
+	}
+	sort.SliceStable(creators, func(i, j int) bool {
+		return natural.Less(strings.ToLower(creators[i].Name), strings.ToLower(creators[j].Name))
+	})
+	return creators, nil
+}
+
+func (s *Store) GetCreator(cid string) (Creator, error) {
+	creators, err := s.GetCreators()
+	if err != nil {
+		return Creator{}, err
+	}
+	for _, c := range creators {
+		if c.CID == cid {
+			return c, nil
+		}
+	}
+	return Creator{}, fmt.Errorf("creator does not exist: %s", cid)
+}
+
+// Returns the series the creator is credited in, in catalog order
+func (s *Store) GetCreatorSeries(cid string) ([]Series, error) {
+	var sids []string
+	err := s.pool.Select(&sids, `SELECT sid FROM series_creators WHERE cid = ?
+		                         UNION SELECT sid FROM entry_creators WHERE cid = ?`, cid, cid)
+	if err != nil {
+		return nil, err
+	}
+	credited := make(map[string]struct{}, len(sids))
+	for _, sid := range sids {
+		credited[sid] = struct{}{}
+	}
+
+	catalog, err := s.GetCatalog()
+	if err != nil {
+		return nil, err
+	}
+	var v []Series
+	for _, sr := range catalog {
+		if _, found := credited[sr.SID]; found {
+			v = append(v, sr)
+		}
+	}
+	return v, nil
+}
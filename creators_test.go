@@ -0,0 +1,91 @@
+package tanuki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAuthorFile(t *testing.T) {
+	require.Equal(t, map[Role]string{RoleWriter: "a"}, parseAuthorFile("a\n"))
+	require.Equal(t, map[Role]string{
+		RoleWriter:     "a, b",
+		RoleArtist:     "b",
+		RoleTranslator: "c (d)",
+		RoleLetterer:   "e",
+	}, parseAuthorFile("a\nb\n\nb (artist)\nc (d) (Translator)\n  e(letterer)  \n"))
+
+	t.Run("applied to series", func(t *testing.T) {
+		sr := Series{Author: "a", Info: SeriesInfo{Artist: "b", Translator: "c"}}
+		sr.applyAuthorFile(parseAuthorFile("d\ne (artist)"))
+		require.Equal(t, []Credit{
+			{Name: "d", Role: RoleWriter},
+			{Name: "e", Role: RoleArtist},
+			{Name: "c", Role: RoleTranslator},
+		}, sr.Credits())
+	})
+}
+
+func TestStore_Creators(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	modTime := time.Now().Round(0) // Strip the monotonic clock reading
+	a := Series{SID: "a", Title: "A", Author: "Writer", ModTime: modTime, Info: SeriesInfo{Artist: "Artist"}}
+	b := Series{SID: "b", Title: "B", Author: "writer, Artist", ModTime: modTime.Add(time.Hour)}
+	eb := Entry{SID: "b", EID: "c", Title: "C", ModTime: modTime, Pages: Pages{},
+		Info: ComicInfo{Translator: "Translator"}}
+	lib := map[Series][]Entry{a: nil, b: {eb}}
+	require.NoError(t, s.PopulateCatalog(lib))
+
+	t.Run("credited creators", func(t *testing.T) {
+		creators, err := s.GetCreators()
+		require.NoError(t, err)
+		require.Equal(t, []Creator{
+			{CID: creatorID("artist"), Name: "Artist", Roles: []Role{RoleWriter, RoleArtist}, Count: 2, ModTime: b.ModTime},
+			{CID: creatorID("translator"), Name: "Translator", Roles: []Role{RoleTranslator}, Count: 1, ModTime: b.ModTime},
+			{CID: creatorID("writer"), Name: "Writer", Roles: []Role{RoleWriter}, Count: 2, ModTime: b.ModTime},
+		}, creators)
+
+		// Creators only credited in an entry still list its series
+		srs, err := s.GetCreatorSeries(creatorID("Translator"))
+		require.NoError(t, err)
+		require.Len(t, srs, 1)
+		require.Equal(t, "B", srs[0].Title)
+	})
+
+	t.Run("overridden author", func(t *testing.T) {
+		require.NoError(t, s.SetSeriesOverride(a.SID, "author", "Someone"))
+		require.NoError(t, s.SetEntryOverride(eb.SID, eb.EID, "author", "Someone Else"))
+		_, err := s.GetCreator(creatorID("Someone"))
+		require.NoError(t, err)
+		_, err = s.GetCreator(creatorID("Someone Else"))
+		require.NoError(t, err)
+
+		// Overrides are still applied after rescanning
+		require.NoError(t, s.PopulateCatalog(lib))
+		c, err := s.GetCreator(creatorID("Writer"))
+		require.NoError(t, err)
+		require.Equal(t, 1, c.Count)
+
+		// Removing the override restores the original author
+		require.NoError(t, s.RemoveSeriesOverride(a.SID, "author"))
+		require.NoError(t, s.RemoveEntryOverride(eb.SID, eb.EID, "author"))
+		_, err = s.GetCreator(creatorID("Someone"))
+		require.Error(t, err)
+		c, err = s.GetCreator(creatorID("Writer"))
+		require.NoError(t, err)
+		require.Equal(t, 2, c.Count)
+	})
+
+	t.Run("removed creators", func(t *testing.T) {
+		require.NoError(t, s.PopulateCatalog(map[Series][]Entry{a: nil}))
+		_, err := s.GetCreator(creatorID("Translator"))
+		require.Error(t, err)
+
+		var count int
+		require.NoError(t, s.pool.Get(&count, `SELECT COUNT(*) FROM creators`))
+		require.Equal(t, 2, count)
+	})
+}
@@ -0,0 +1,200 @@
+package tanuki
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maruel/natural"
+)
+
+// Duplicates
+
+// Series and entries are identified by their names, so ones
+// with the same name get the same ID and would overwrite each
+// other, e.g. entries with the same name in different folders
+// of a series. Series are named after the folders and files in
+// the library's root, so they can't clash yet, but they're still
+// resolved in case the library ever spans several roots.
+// By default every duplicate is kept, the first keeps the ID
+// and the rest are identified by their path instead. Admins
+// can choose to prefer one of them, so the rest are ignored
+type Duplicate struct {
+	SID      string
+	EID      string   // Empty if the series are duplicates
+	Paths    []string // Paths of the duplicates in natural order
+	Prefer   string   // Path of the duplicate which was kept, empty if they all were
+	Resolved bool     // Whether an admin has chosen how to resolve it
+}
+
+func (s *Store) getDuplicateResolutions(q sqlx.Queryer) (map[entryKey]string, error) {
+	var rows []struct {
+		SID    string
+		EID    string
+		Prefer string
+	}
+	if err := sqlx.Select(q, &rows, `SELECT sid, eid, prefer FROM duplicates`); err != nil {
+		return nil, err
+	}
+	m := make(map[entryKey]string, len(rows))
+	for _, r := range rows {
+		m[entryKey{r.SID, r.EID}] = r.Prefer
+	}
+	return m, nil
+}
+
+// Resolutions persist across scans, the EID is empty for
+// series, and prefer is empty to keep every duplicate
+func (s *Store) ResolveDuplicate(sid, eid, prefer string) error {
+	return s.tx(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`INSERT INTO duplicates (sid, eid, prefer) VALUES (?, ?, ?)
+			               ON CONFLICT (sid, eid) DO UPDATE SET prefer=excluded.prefer`, sid, eid, prefer)
+		return err
+	})
+}
+
+// Returns the library with its duplicates resolved, and the duplicates
+// which were found. Series are resolved before their entries, since
+// resolving the series can change the SID of their entries
+func (s *Store) ResolveDuplicates(lib map[Series][]Entry) (map[Series][]Entry, []Duplicate, error) {
+	resolutions, err := s.getDuplicateResolutions(s.pool)
+	if err != nil {
+		return nil, nil, err
+	}
+	lib, dups := resolveDuplicates(lib, resolutions)
+	return lib, dups, nil
+}
+
+func resolveDuplicates(lib map[Series][]Entry, resolutions map[entryKey]string) (map[Series][]Entry, []Duplicate) {
+	var dups []Duplicate
+
+	bySID := make(map[string][]Series)
+	for sr := range lib {
+		bySID[sr.SID] = append(bySID[sr.SID], sr)
+	}
+	resolved := make(map[Series][]Entry, len(lib))
+	for _, sid := range sortedKeys(bySID) {
+		group := bySID[sid]
+		sort.SliceStable(group, func(i, j int) bool {
+			return natural.Less(group[i].Path, group[j].Path)
+		})
+		paths := make([]string, len(group))
+		for i, sr := range group {
+			paths[i] = sr.Path
+		}
+
+		// Only the first series keeps the ID and title,
+		// titles have to be unique as well. Groups only
+		// have one series while there's a single root
+		keep := func(i int, sr Series) {
+			entries := lib[sr]
+			if i > 0 {
+				sr.SID = Sha256(sr.Path)
+				sr.Title = fmt.Sprintf("%s (%s)", sr.Title, filepath.Base(sr.Path))
+				entries = slices.Clone(entries)
+				for j := range entries {
+					entries[j].SID = sr.SID
+				}
+			}
+			resolved[sr] = entries
+		}
+		if len(group) == 1 {
+			keep(0, group[0])
+			continue
+		}
+
+		d := newDuplicate(entryKey{SID: group[0].SID}, paths, resolutions)
+		dups = append(dups, d)
+		for i, sr := range group {
+			if d.Prefer == "" {
+				keep(i, sr)
+			} else if d.Prefer == sr.Path {
+				keep(0, sr)
+			}
+		}
+	}
+
+	for sr, entries := range resolved {
+		byEID := make(map[string][]int)
+		for i, e := range entries {
+			byEID[e.EID] = append(byEID[e.EID], i)
+		}
+
+		kept := make([]Entry, 0, len(entries))
+		handled := make(map[string]struct{})
+		for _, e := range entries {
+			group := byEID[e.EID]
+			if len(group) == 1 {
+				kept = append(kept, e)
+				continue
+			}
+			// The group's handled with its first entry
+			if _, found := handled[e.EID]; found {
+				continue
+			}
+			handled[e.EID] = struct{}{}
+
+			sort.SliceStable(group, func(i, j int) bool {
+				return natural.Less(entries[group[i]].Archive, entries[group[j]].Archive)
+			})
+			paths := make([]string, len(group))
+			for j, k := range group {
+				paths[j] = entries[k].Archive
+			}
+
+			d := newDuplicate(entryKey{SID: sr.SID, EID: e.EID}, paths, resolutions)
+			dups = append(dups, d)
+			for j, k := range group {
+				dup := entries[k]
+				if d.Prefer != "" && d.Prefer != dup.Archive {
+					continue
+				}
+				if d.Prefer == "" && j > 0 {
+					dup.EID = Sha256(dup.Archive)
+				}
+				kept = append(kept, dup)
+			}
+		}
+		resolved[sr] = kept
+	}
+
+	// Series were resolved in the order of their SIDs
+	// but entries weren't, so the order is made stable
+	sort.SliceStable(dups, func(i, j int) bool {
+		if dups[i].SID != dups[j].SID {
+			return dups[i].SID < dups[j].SID
+		}
+		return dups[i].EID < dups[j].EID
+	})
+
+	return resolved, dups
+}
+
+func newDuplicate(k entryKey, paths []string, resolutions map[entryKey]string) Duplicate {
+	d := Duplicate{SID: k.SID, EID: k.EID, Paths: paths}
+	prefer, found := resolutions[k]
+	switch {
+	case !found:
+	case prefer == "" || slices.Contains(paths, prefer):
+		d.Prefer = prefer
+		d.Resolved = true
+	default:
+		// The preferred duplicate could've been removed
+		// or renamed since it was chosen
+		slog.Warn("Preferred duplicate does not exist", slog.String("sid", k.SID),
+			slog.String("eid", k.EID), slog.String("prefer", prefer))
+	}
+	return d
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,152 @@
+package tanuki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveDuplicates(t *testing.T) {
+	folder := Series{SID: "a", Title: "A", Path: "/lib/A"}
+	oneShot := Series{SID: "a", Title: "A", Path: "/lib/A.zip"}
+	other := Series{SID: "b", Title: "B", Path: "/lib/B"}
+	lib := map[Series][]Entry{
+		folder:  {{SID: "a", EID: "c", Archive: "/lib/A/c.zip"}},
+		oneShot: {{SID: "a", EID: "a", Archive: "/lib/A.zip"}},
+		other: {
+			{SID: "b", EID: "d", Archive: "/lib/B/Season 2/d.zip"},
+			{SID: "b", EID: "e", Archive: "/lib/B/e.zip"},
+			{SID: "b", EID: "d", Archive: "/lib/B/Season 1/d.zip"},
+		},
+	}
+
+	t.Run("unresolved", func(t *testing.T) {
+		resolved, dups := resolveDuplicates(lib, nil)
+		require.Equal(t, []Duplicate{
+			{SID: "a", Paths: []string{"/lib/A", "/lib/A.zip"}},
+			{SID: "b", EID: "d", Paths: []string{"/lib/B/Season 1/d.zip", "/lib/B/Season 2/d.zip"}},
+		}, dups)
+
+		// Every duplicate is kept, the first keeps the ID
+		renamed := oneShot
+		renamed.SID = Sha256(oneShot.Path)
+		renamed.Title = "A (A.zip)"
+		require.Equal(t, map[Series][]Entry{
+			folder:  lib[folder],
+			renamed: {{SID: renamed.SID, EID: "a", Archive: "/lib/A.zip"}},
+			other: {
+				{SID: "b", EID: "d", Archive: "/lib/B/Season 1/d.zip"},
+				{SID: "b", EID: Sha256("/lib/B/Season 2/d.zip"), Archive: "/lib/B/Season 2/d.zip"},
+				{SID: "b", EID: "e", Archive: "/lib/B/e.zip"},
+			},
+		}, resolved)
+
+		// The library isn't modified
+		require.Equal(t, "a", lib[oneShot][0].SID)
+	})
+
+	t.Run("preferred", func(t *testing.T) {
+		resolved, dups := resolveDuplicates(lib, map[entryKey]string{
+			{SID: "a"}:           "/lib/A.zip",
+			{SID: "b", EID: "d"}: "/lib/B/Season 2/d.zip",
+		})
+		require.Equal(t, []Duplicate{
+			{SID: "a", Paths: []string{"/lib/A", "/lib/A.zip"}, Prefer: "/lib/A.zip", Resolved: true},
+			{SID: "b", EID: "d", Paths: []string{"/lib/B/Season 1/d.zip", "/lib/B/Season 2/d.zip"},
+				Prefer: "/lib/B/Season 2/d.zip", Resolved: true},
+		}, dups)
+
+		// The preferred duplicate keeps the ID
+		require.Equal(t, map[Series][]Entry{
+			oneShot: lib[oneShot],
+			other: {
+				{SID: "b", EID: "d", Archive: "/lib/B/Season 2/d.zip"},
+				{SID: "b", EID: "e", Archive: "/lib/B/e.zip"},
+			},
+		}, resolved)
+	})
+
+	t.Run("kept", func(t *testing.T) {
+		resolved, dups := resolveDuplicates(lib, map[entryKey]string{
+			{SID: "a"}: "",
+		})
+		require.True(t, dups[0].Resolved)
+		require.False(t, dups[1].Resolved)
+		require.Len(t, resolved, 3)
+	})
+
+	t.Run("preferred duplicate removed", func(t *testing.T) {
+		resolved, dups := resolveDuplicates(lib, map[entryKey]string{
+			{SID: "a"}: "/lib/A.cbz",
+		})
+		require.False(t, dups[0].Resolved)
+		require.Empty(t, dups[0].Prefer)
+		require.Len(t, resolved, 3)
+	})
+}
+
+func TestStore_ResolveDuplicates(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	modTime := time.Now().Round(0) // Strip the monotonic clock reading
+	a := Series{SID: "a", Title: "A", ModTime: modTime, Path: "/lib/A"}
+	newEntry := func(archive string) Entry {
+		return Entry{SID: "a", EID: "b", Title: "b", ModTime: modTime, Archive: archive, Pages: Pages{},
+			Fingerprint: archive}
+	}
+	lib := map[Series][]Entry{a: {newEntry("/lib/A/1/b.zip"), newEntry("/lib/A/2/b.zip")}}
+
+	populate := func(t *testing.T) []Entry {
+		resolved, dups, err := s.ResolveDuplicates(lib)
+		require.NoError(t, err)
+		require.Len(t, dups, 1)
+		require.NoError(t, s.PopulateCatalog(resolved))
+		es, err := s.GetEntries("a")
+		require.NoError(t, err)
+		return es
+	}
+
+	// Neither duplicate overwrites the other
+	es := populate(t)
+	require.Len(t, es, 2)
+	require.Equal(t, "b", es[0].EID)
+	require.Equal(t, Sha256("/lib/A/2/b.zip"), es[1].EID)
+
+	// Resolutions persist across scans
+	require.NoError(t, s.ResolveDuplicate("a", "b", "/lib/A/2/b.zip"))
+	es = populate(t)
+	require.Len(t, es, 1)
+	require.Equal(t, "/lib/A/2/b.zip", es[0].Archive)
+
+	require.NoError(t, s.ResolveDuplicate("a", "b", ""))
+	require.Len(t, populate(t), 2)
+}
+
+func TestStore_ResolveDuplicates_Series(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	modTime := time.Now().Round(0) // Strip the monotonic clock reading
+	newSeries := func(path string) (Series, []Entry) {
+		sr := Series{SID: "a", Title: "A", ModTime: modTime, Path: path}
+		return sr, []Entry{{SID: "a", EID: "b", Title: "b", ModTime: modTime, Archive: path + "/b.zip",
+			Pages: Pages{}, Fingerprint: path}}
+	}
+	folder, folderEntries := newSeries("/lib/A")
+	other, otherEntries := newSeries("/lib/B/A")
+	lib := map[Series][]Entry{folder: folderEntries, other: otherEntries}
+
+	// Both series are added even though they share a title
+	resolved, dups, err := s.ResolveDuplicates(lib)
+	require.NoError(t, err)
+	require.Len(t, dups, 1)
+	require.NoError(t, s.PopulateCatalog(resolved))
+	ctl, err := s.GetCatalog()
+	require.NoError(t, err)
+	require.Len(t, ctl, 2)
+	require.Equal(t, "A", ctl[0].Title)
+	require.Equal(t, "A (A)", ctl[1].Title)
+	require.Equal(t, Sha256("/lib/B/A"), ctl[1].SID)
+}
@@ -0,0 +1,97 @@
+package tanuki
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maruel/natural"
+)
+
+// Folders
+
+// Subfolders within a series are browsable like the series
+// itself, they're made from the folders of the series' entries
+// so they only exist if they contain an entry somewhere inside
+type Folder struct {
+	FID     string
+	SID     string
+	Path    string    // Slash separated path within the series
+	Title   string    // Name of the folder itself
+	Count   int       // Number of entries inside the folder
+	ModTime time.Time // Latest mod time of the entries inside
+}
+
+func folderID(path string) string {
+	return Sha256(path)
+}
+
+// Whether the folder is, or is inside, the parent
+func inFolder(folder, parent string) bool {
+	return parent == "" || folder == parent || strings.HasPrefix(folder, parent+"/")
+}
+
+// Returns the folders directly inside the parent, which
+// is empty for the series' root, in natural order
+func subfolders(entries []Entry, parent string) []Folder {
+	var folders []Folder
+	index := make(map[string]int)
+	for _, e := range entries {
+		if e.Folder == parent || !inFolder(e.Folder, parent) {
+			continue
+		}
+
+		// Only the first folder below the parent is used
+		rel := strings.TrimPrefix(strings.TrimPrefix(e.Folder, parent), "/")
+		name, _, _ := strings.Cut(rel, "/")
+		p := path.Join(parent, name)
+
+		i, found := index[p]
+		if !found {
+			i = len(folders)
+			index[p] = i
+			folders = append(folders, Folder{FID: folderID(p), SID: e.SID, Path: p, Title: name})
+		}
+		folders[i].Count++
+		if e.ModTime.After(folders[i].ModTime) {
+			folders[i].ModTime = e.ModTime
+		}
+	}
+	sort.SliceStable(folders, func(i, j int) bool {
+		return natural.Less(folders[i].Title, folders[j].Title)
+	})
+	return folders
+}
+
+// Returns the entries directly inside the folder
+func folderEntries(entries []Entry, folder string) []Entry {
+	var v []Entry
+	for _, e := range entries {
+		if e.Folder == folder {
+			v = append(v, e)
+		}
+	}
+	return v
+}
+
+func (s *Store) GetFolder(sid, fid string) (Folder, error) {
+	entries, err := s.GetEntries(sid)
+	if err != nil {
+		return Folder{}, err
+	}
+
+	// Walk down the tree from the series' root,
+	// since folders are only known by their ID
+	queue := subfolders(entries, "")
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+		if f.FID == fid {
+			return f, nil
+		}
+		queue = append(queue, subfolders(entries, f.Path)...)
+	}
+	return Folder{}, fmt.Errorf("folder does not exist: %s", fid)
+}
@@ -0,0 +1,62 @@
+package tanuki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubfolders(t *testing.T) {
+	modTime := time.Now()
+	entries := []Entry{
+		{SID: "a", EID: "1"},
+		{SID: "a", EID: "2", Folder: "Season 10", ModTime: modTime},
+		{SID: "a", EID: "3", Folder: "Season 2/Extras", ModTime: modTime.Add(time.Hour)},
+		{SID: "a", EID: "4", Folder: "Season 2"},
+		{SID: "a", EID: "5", Folder: "Season 20"},
+	}
+
+	require.Equal(t, []Folder{
+		{FID: folderID("Season 2"), SID: "a", Path: "Season 2", Title: "Season 2", Count: 2, ModTime: modTime.Add(time.Hour)},
+		{FID: folderID("Season 10"), SID: "a", Path: "Season 10", Title: "Season 10", Count: 1, ModTime: modTime},
+		{FID: folderID("Season 20"), SID: "a", Path: "Season 20", Title: "Season 20", Count: 1},
+	}, subfolders(entries, ""))
+	require.Equal(t, []Folder{
+		{FID: folderID("Season 2/Extras"), SID: "a", Path: "Season 2/Extras", Title: "Extras", Count: 1, ModTime: modTime.Add(time.Hour)},
+	}, subfolders(entries, "Season 2"))
+	require.Empty(t, subfolders(entries, "Season 2/Extras"))
+
+	require.Equal(t, []Entry{entries[0]}, folderEntries(entries, ""))
+	require.Equal(t, []Entry{entries[3]}, folderEntries(entries, "Season 2"))
+}
+
+func TestStore_GetFolder(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	lib, err := ParseLibrary("tests/lib-nested", LibraryOptions{})
+	require.NoError(t, err)
+	require.NoError(t, s.PopulateCatalog(lib))
+
+	entries, err := s.GetEntries(akiraSeries.SID)
+	require.NoError(t, err)
+	folders := make(map[string]string)
+	for _, e := range entries {
+		folders[e.Title] = e.Folder
+	}
+	require.Equal(t, map[string]string{
+		"Volume 01": "",
+		"Volume 02": "Season 1",
+		"v1":        "Season 1/Extras",
+		"v2":        "Side Stories",
+	}, folders)
+
+	f, err := s.GetFolder(akiraSeries.SID, folderID("Season 1/Extras"))
+	require.NoError(t, err)
+	require.Equal(t, "Extras", f.Title)
+	require.Equal(t, 1, f.Count)
+
+	_, err = s.GetFolder(akiraSeries.SID, folderID("Extras"))
+	require.Error(t, err)
+}
@@ -0,0 +1,149 @@
+package tanuki
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Fingerprints
+
+// Entries are fingerprinted by their contents so they're recognised
+// after being renamed or moved. Like a zip's central directory, the
+// fingerprint covers the size of the archive and the names of its
+// pages, which is cheap since none of the pages have to be read
+func fingerprint(e Entry) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", e.Filesize)
+	for _, p := range e.Pages {
+		fmt.Fprintf(h, "%s\n%d\n", p.Path, p.Size)
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Renames
+
+type entryKey struct {
+	SID string
+	EID string
+}
+
+type storedEntry struct {
+	SID         string
+	EID         string
+	Title       string
+	Fingerprint string
+}
+
+// EIDs are made from the entry's title, so renaming an entry would
+// otherwise change its EID. Instead, entries keep the EID of the
+// stored entry with the same fingerprint which has gone missing.
+// Entries moved to a different series are recorded in moved, with
+// the SID of the series they were moved from. Entries are resolved
+// in the order given, so the resolution is deterministic. Only the
+// stored entries of series in the scope can be claimed, unless it's nil
+func (s *Store) resolveEntries(tx *sqlx.Tx, series []Series, input map[Series][]Entry, scope map[string]struct{}) ([][]Entry, map[entryKey]string, error) {
+	var all []storedEntry
+	if err := tx.Select(&all, `SELECT sid, eid, title, fingerprint FROM entries`); err != nil {
+		return nil, nil, err
+	}
+	rows := make([]storedEntry, 0, len(all))
+	stored := make(map[entryKey]storedEntry, len(all))
+	for _, r := range all {
+		stored[entryKey{r.SID, r.EID}] = r
+		if inScope(scope, r.SID) {
+			rows = append(rows, r)
+		}
+	}
+
+	resolved := make([][]Entry, len(series))
+	done := make([][]bool, len(series))
+	for i, sr := range series {
+		resolved[i] = append([]Entry(nil), input[sr]...)
+		done[i] = make([]bool, len(resolved[i]))
+	}
+	claimed := make(map[entryKey]struct{})
+	moved := make(map[entryKey]string)
+
+	// Entries which haven't changed keep their EID, stores
+	// which predate fingerprints have none to compare
+	for i := range resolved {
+		for j, e := range resolved[i] {
+			k := entryKey{e.SID, e.EID}
+			if r, found := stored[k]; found && (r.Fingerprint == e.Fingerprint || r.Fingerprint == "") {
+				claimed[k] = struct{}{}
+				done[i][j] = true
+			}
+		}
+	}
+
+	// Renamed or moved entries keep the EID of a stored entry
+	// with the same fingerprint which no longer exists
+	missing := make(map[string][]storedEntry)
+	for _, r := range rows {
+		if _, found := claimed[entryKey{r.SID, r.EID}]; !found && r.Fingerprint != "" {
+			missing[r.Fingerprint] = append(missing[r.Fingerprint], r)
+		}
+	}
+	for i := range resolved {
+		for j, e := range resolved[i] {
+			if done[i][j] {
+				continue
+			}
+			for n, r := range missing[e.Fingerprint] {
+				k := entryKey{e.SID, r.EID}
+				if _, found := claimed[k]; found {
+					continue
+				}
+				// Moves can't replace an existing entry
+				if _, found := stored[k]; found && r.SID != e.SID {
+					continue
+				}
+
+				slog.Info("Detected renamed entry", slog.String("sid", e.SID), slog.String("eid", r.EID),
+					slog.String("old", r.Title), slog.String("new", e.Title))
+				if r.SID != e.SID {
+					moved[k] = r.SID
+				}
+				resolved[i][j].EID = r.EID
+				claimed[k] = struct{}{}
+				done[i][j] = true
+				missing[e.Fingerprint] = append(missing[e.Fingerprint][:n:n], missing[e.Fingerprint][n+1:]...)
+				break
+			}
+		}
+	}
+
+	// New entries use their own EID, unless another
+	// entry has claimed it, e.g. a renamed entry
+	for i := range resolved {
+		for j, e := range resolved[i] {
+			if done[i][j] {
+				continue
+			}
+			k := entryKey{e.SID, e.EID}
+			if _, found := claimed[k]; found {
+				k.EID = Sha256(e.Title + "\x00" + e.Fingerprint)
+				resolved[i][j].EID = k.EID
+			}
+			claimed[k] = struct{}{}
+		}
+	}
+
+	return resolved, moved, nil
+}
+
+// Moves the stored entry, and everything which refers to
+// it, to its new series before it's updated
+func (s *Store) moveEntry(tx *sqlx.Tx, fromSID, toSID, eid string) error {
+	_, err := tx.Exec(`UPDATE entries SET sid = ? WHERE sid = ? AND eid = ?`, toSID, fromSID, eid)
+	if err != nil {
+		return err
+	}
+	// Overrides don't reference the entries table
+	_, err = tx.Exec(`UPDATE OR REPLACE overrides SET sid = ? WHERE sid = ? AND eid = ?`, toSID, fromSID, eid)
+	return err
+}
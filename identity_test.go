@@ -0,0 +1,116 @@
+package tanuki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	e := Entry{Filesize: 10, Pages: Pages{{Path: "a.png", Size: 5}}}
+	require.Equal(t, fingerprint(e), fingerprint(e))
+
+	// Renaming the archive doesn't change the fingerprint
+	renamed := e
+	renamed.Title = "Renamed"
+	renamed.Archive = "renamed.zip"
+	require.Equal(t, fingerprint(e), fingerprint(renamed))
+
+	// Changing the contents does
+	changed := e
+	changed.Pages = Pages{{Path: "b.png", Size: 5}}
+	require.NotEqual(t, fingerprint(e), fingerprint(changed))
+}
+
+func TestStore_RenamedEntries(t *testing.T) {
+	modTime := time.Now().Round(0) // Strip the monotonic clock reading
+	a := Series{SID: "a", Title: "A", ModTime: modTime}
+	b := Series{SID: "b", Title: "B", ModTime: modTime}
+	newEntry := func(sid, title, fp string) Entry {
+		return Entry{SID: sid, EID: Sha256(title), Title: title, ModTime: modTime, Pages: Pages{}, Fingerprint: fp}
+	}
+	mustGetEIDs := func(t *testing.T, s *Store, sid string) []string {
+		es, err := s.GetEntries(sid)
+		require.NoError(t, err)
+		var eids []string
+		for _, e := range es {
+			eids = append(eids, e.EID)
+		}
+		return eids
+	}
+
+	t.Run("renamed in the same series", func(t *testing.T) {
+		s := mustOpenStoreMem(t)
+		defer mustCloseStore(t, s)
+
+		e := newEntry("a", "v1", "fp")
+		require.NoError(t, s.PopulateCatalog(map[Series][]Entry{a: {e}}))
+		require.NoError(t, s.SetEntryOverride(e.SID, e.EID, "title", "Overridden"))
+		_, err := s.pool.Exec(`INSERT INTO thumbnails (sid, eid, mod_time, page, data) VALUES (?, ?, ?, 0, ?)`,
+			e.SID, e.EID, e.ModTime, []byte{1})
+		require.NoError(t, err)
+
+		require.NoError(t, s.PopulateCatalog(map[Series][]Entry{a: {newEntry("a", "Volume 1", "fp")}}))
+		require.Equal(t, []string{e.EID}, mustGetEIDs(t, s, "a"))
+
+		// The overrides and thumbnail are kept
+		renamed, err := s.GetEntry(e.SID, e.EID)
+		require.NoError(t, err)
+		require.Equal(t, "Overridden", renamed.Title)
+		var count int
+		require.NoError(t, s.pool.Get(&count, `SELECT COUNT(*) FROM thumbnails WHERE sid = ? AND eid = ?`, e.SID, e.EID))
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("moved to another series", func(t *testing.T) {
+		s := mustOpenStoreMem(t)
+		defer mustCloseStore(t, s)
+
+		e := newEntry("a", "v1", "fp")
+		require.NoError(t, s.PopulateCatalog(map[Series][]Entry{a: {e}, b: nil}))
+		require.NoError(t, s.SetEntryOverride(e.SID, e.EID, "title", "Overridden"))
+
+		require.NoError(t, s.PopulateCatalog(map[Series][]Entry{a: nil, b: {newEntry("b", "v1 (moved)", "fp")}}))
+		require.Empty(t, mustGetEIDs(t, s, "a"))
+		require.Equal(t, []string{e.EID}, mustGetEIDs(t, s, "b"))
+
+		moved, err := s.GetEntry("b", e.EID)
+		require.NoError(t, err)
+		require.Equal(t, "Overridden", moved.Title)
+		ovs, err := s.getOverrides(s.pool, "a")
+		require.NoError(t, err)
+		require.Empty(t, ovs)
+	})
+
+	t.Run("new entry reusing a renamed entry's name", func(t *testing.T) {
+		s := mustOpenStoreMem(t)
+		defer mustCloseStore(t, s)
+
+		e := newEntry("a", "v1", "fp")
+		require.NoError(t, s.PopulateCatalog(map[Series][]Entry{a: {e}}))
+
+		// v1 is renamed to v2, and a different v1 is added
+		input := map[Series][]Entry{a: {newEntry("a", "v1", "other"), newEntry("a", "v2", "fp")}}
+		require.NoError(t, s.PopulateCatalog(input))
+		require.Equal(t, []string{Sha256("v1\x00other"), e.EID}, mustGetEIDs(t, s, "a"))
+
+		// Rescanning is deterministic
+		require.NoError(t, s.PopulateCatalog(input))
+		require.Equal(t, []string{Sha256("v1\x00other"), e.EID}, mustGetEIDs(t, s, "a"))
+	})
+
+	t.Run("entries without fingerprints", func(t *testing.T) {
+		s := mustOpenStoreMem(t)
+		defer mustCloseStore(t, s)
+
+		// Entries stored before fingerprints existed keep their EID
+		e := newEntry("a", "v1", "")
+		require.NoError(t, s.PopulateCatalog(map[Series][]Entry{a: {e}}))
+		require.NoError(t, s.PopulateCatalog(map[Series][]Entry{a: {newEntry("a", "v1", "fp")}}))
+		require.Equal(t, []string{e.EID}, mustGetEIDs(t, s, "a"))
+		stored, err := s.GetEntry(e.SID, e.EID)
+		require.NoError(t, err)
+		require.Equal(t, "fp", stored.Fingerprint)
+	})
+}
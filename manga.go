@@ -1,7 +1,7 @@
 package tanuki
 
 import (
-	"archive/zip"
+	"context"
 	"crypto/sha256"
 	"database/sql/driver"
 	"encoding/base64"
@@ -14,8 +14,10 @@ import (
 	"mime"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/maruel/natural"
@@ -31,6 +33,17 @@ type Page struct {
 	// encoding? The only alternate encoding we support
 	// is CP437
 	NonUtf8 bool
+	// Where the page's data is located within the archive,
+	// this is only recorded for formats which can seek
+	// straight to the page, e.g. uncompressed tarballs
+	Offset int64 `json:",omitempty"`
+	Size   int64 `json:",omitempty"`
+	// Measured when the entry's parsed, so readers can lay
+	// out the pages without having to download them first
+	Width  int   `json:",omitempty"`
+	Height int   `json:",omitempty"`
+	Bytes  int64 `json:",omitempty"`
+	Wide   bool  `json:",omitempty"` // e.g. a double-page spread
 }
 
 type Pages []Page
@@ -50,13 +63,26 @@ func (p *Pages) Scan(src any) error {
 // Entry
 
 type Entry struct {
-	EID      string
-	SID      string
-	Title    string
-	ModTime  time.Time
-	Archive  string
-	Filesize int64
-	Pages    Pages
+	EID       string
+	SID       string
+	Title     string
+	Author    string
+	ModTime   time.Time
+	Archive   string
+	Filesize  int64
+	Pages     Pages
+	Info      ComicInfo
+	Volume    Span
+	Chapter   Span
+	CoverPage int
+	// Identifies the entry by its contents
+	Fingerprint string
+	// Slash separated path of the folder the entry's
+	// in within its series, empty for the series' root
+	Folder string
+
+	// Only set by overrides
+	SortTitle string
 }
 
 var validImageTypes = map[string]struct{}{
@@ -67,6 +93,35 @@ var validImageTypes = map[string]struct{}{
 	"image/bmp":  {},
 }
 
+func isImage(name string) bool {
+	_, found := validImageTypes[mime.TypeByExtension(filepath.Ext(name))]
+	return found
+}
+
+// Image folders are leaf folders which contain images
+func isImageDir(path string) bool {
+	items, err := os.ReadDir(path)
+	if err != nil {
+		return false
+	}
+
+	hasImages := false
+	for _, item := range items {
+		if item.IsDir() {
+			return false
+		}
+		if !strings.HasPrefix(item.Name(), ".") && isImage(item.Name()) {
+			hasImages = true
+		}
+	}
+	return hasImages
+}
+
+// Bumped whenever parsing changes what's recorded about
+// entries, so ones parsed by an older version of tanuki
+// aren't reused by incremental scans
+const parseVersion = 1
+
 func ParseEntry(path string) (Entry, error) {
 	slog.Debug("Parsing entry", slog.String("path", path))
 
@@ -78,63 +133,203 @@ func ParseEntry(path string) (Entry, error) {
 	if err != nil {
 		return Entry{}, err
 	}
-	title := strings.TrimSuffix(stat.Name(), filepath.Ext(stat.Name()))
+	title := entryTitle(stat)
+	size, modTime, err := statEntry(abs)
+	if err != nil {
+		return Entry{}, err
+	}
 
 	e := Entry{
 		EID:      Sha256(title),
 		Title:    title,
 		Archive:  abs,
-		Filesize: stat.Size(),
-		ModTime:  stat.ModTime().Round(0), // Strip the monotonic clock reading
+		Filesize: size,
+		ModTime:  modTime,
 		Pages:    make([]Page, 0),
 	}
 
-	r, err := zip.OpenReader(abs)
+	a, err := openArchive(abs, nil)
 	if err != nil {
 		return Entry{}, err
 	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		fi := f.FileInfo()
-		if !fi.IsDir() && !strings.HasPrefix(fi.Name(), ".") {
-			m := mime.TypeByExtension(filepath.Ext(fi.Name()))
-			if _, found := validImageTypes[m]; !found {
-				return Entry{}, fmt.Errorf("invalid image mime for page %s: %s", fi.Name(), m)
-			}
+	defer a.Close()
 
-			name := f.Name
-			if f.NonUTF8 {
-				name, err = decodeCP437(f.Name)
-				if err != nil {
-					return Entry{}, fmt.Errorf("invalid CP437 name for page %s: %w", fi.Name(), err)
-				}
-			}
-			e.Pages = append(e.Pages, Page{
-				Path:    name,
-				Mime:    m,
-				NonUtf8: f.NonUTF8,
-			})
+	files, err := a.files()
+	if err != nil {
+		return Entry{}, err
+	}
+	var comicInfo *archiveFile
+	for _, f := range files {
+		base := filepath.Base(f.Name)
+		if strings.HasPrefix(base, ".") {
+			continue
+		}
+		if isComicInfo(base) {
+			comicInfo = &f
+			continue
 		}
 
+		m := mime.TypeByExtension(filepath.Ext(base))
+		if _, found := validImageTypes[m]; !found {
+			return Entry{}, fmt.Errorf("invalid image mime for page %s: %s", base, m)
+		}
+		e.Pages = append(e.Pages, Page{
+			Path:    f.Name,
+			Mime:    m,
+			NonUtf8: f.NonUtf8,
+			Offset:  f.Offset,
+			Size:    f.Size,
+			Bytes:   f.Bytes,
+		})
 	}
 	if len(e.Pages) == 0 {
 		return Entry{}, fmt.Errorf("archive contains no pages")
 	}
+	if m, ok := a.(metadataArchive); ok {
+		meta := m.metadata()
+		if meta.Title != "" {
+			e.Title = meta.Title
+		}
+		e.Author = meta.Author
+	}
+	if comicInfo != nil {
+		data, err := a.read(Page{Path: comicInfo.Name, NonUtf8: comicInfo.NonUtf8,
+			Offset: comicInfo.Offset, Size: comicInfo.Size})
+		if err != nil {
+			return Entry{}, fmt.Errorf("read %s: %w", comicInfoFilename, err)
+		}
+		// Bad metadata shouldn't stop the entry from being read
+		e.Info, err = parseComicInfo(data)
+		if err != nil {
+			slog.Warn("Could not parse metadata", slog.String("path", path), slog.Any("err", err))
+		}
+		if e.Author == "" {
+			e.Author = e.Info.Writer
+		}
+	}
 
-	// Go reads the ZIP files in string-sorted order, which means
-	// they're read as out-of-order in some cases because they're
-	// "natural" sorted. Some archives also have problems with bad
-	// casing, so we just lowercase everything to be safe
-	sort.SliceStable(e.Pages, func(i, j int) bool {
-		a := strings.TrimSuffix(e.Pages[i].Path, filepath.Ext(e.Pages[i].Path))
-		b := strings.TrimSuffix(e.Pages[j].Path, filepath.Ext(e.Pages[j].Path))
-		return natural.Less(strings.ToLower(a), strings.ToLower(b))
-	})
+	// The filename takes precedence over metadata since
+	// metadata doesn't support ranges of numbers
+	e.Volume, e.Chapter = parseNumbers(title)
+	if !e.Volume.Valid && e.Info.Volume > 0 {
+		e.Volume = Span{Start: float64(e.Info.Volume), End: float64(e.Info.Volume), Valid: true}
+	}
+	if !e.Chapter.Valid && e.Info.Number != "" {
+		e.Chapter, _ = parseSpan(e.Info.Number)
+	}
+
+	// Formats like EPUB and PDF already list their pages in
+	// reading order, otherwise archives store their files in
+	// whatever order they were added, which means they're read
+	// as out-of-order in some cases because they're "natural"
+	// sorted. Some archives also have problems with bad casing,
+	// so we just lowercase everything to be safe
+	if t, _ := archiveTypeOf(abs); !t.Format.ordered() {
+		sort.SliceStable(e.Pages, func(i, j int) bool {
+			a := strings.TrimSuffix(e.Pages[i].Path, filepath.Ext(e.Pages[i].Path))
+			b := strings.TrimSuffix(e.Pages[j].Path, filepath.Ext(e.Pages[j].Path))
+			return natural.Less(strings.ToLower(a), strings.ToLower(b))
+		})
+	}
+
+	measurePages(a, e.Pages)
+	e.CoverPage = chooseCover(a, e.Pages, e.Info)
+	e.Fingerprint = fingerprint(e)
 
 	return e, nil
 }
 
+func entryTitle(stat fs.FileInfo) string {
+	if stat.IsDir() {
+		return stat.Name()
+	}
+	return strings.TrimSuffix(stat.Name(), archiveExt(stat.Name()))
+}
+
+// Returns the size and modification time of an entry, a folder's
+// own size and modification time don't reflect the images inside
+// of it, so the size of its pages and the latest modification time
+// of its contents are used instead
+func statEntry(path string) (int64, time.Time, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	modTime := stat.ModTime().Round(0) // Strip the monotonic clock reading
+	if !stat.IsDir() {
+		return stat.Size(), modTime, nil
+	}
+
+	items, err := os.ReadDir(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	var size int64
+	for _, item := range items {
+		name := item.Name()
+		if !item.Type().IsRegular() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		image := isImage(name)
+		if !image && !isComicInfo(name) {
+			continue
+		}
+		info, err := item.Info()
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		if image {
+			size += info.Size()
+		}
+		if mt := info.ModTime().Round(0); mt.After(modTime) {
+			modTime = mt
+		}
+	}
+	return size, modTime, nil
+}
+
+// Entries which haven't changed since they were last parsed,
+// i.e. their path, size and modification time still match,
+// are reused rather than parsed again. Known entries are keyed
+// by their archive
+func parseKnownEntry(path string, known map[string]Entry) (Entry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	e, found := known[abs]
+	if !found {
+		return ParseEntry(path)
+	}
+	stat, err := os.Stat(abs)
+	if err != nil {
+		return Entry{}, err
+	}
+	size, modTime, err := statEntry(abs)
+	if err != nil {
+		return Entry{}, err
+	}
+	if e.Filesize != size || !e.ModTime.Equal(modTime) {
+		return ParseEntry(path)
+	}
+
+	// The entry's IDs and folder depend on where it is and
+	// what else is in the library, so they're set again
+	slog.Debug("Reusing unchanged entry", slog.String("path", path))
+	e.EID = Sha256(entryTitle(stat))
+	e.SID = ""
+	e.Folder = ""
+	return e, nil
+}
+
+// Titles are sorted by their sort title if it's set
+func sortTitle(title, sortTitle string) string {
+	if sortTitle != "" {
+		return sortTitle
+	}
+	return title
+}
+
 // Series
 
 type Series struct {
@@ -142,14 +337,177 @@ type Series struct {
 	Title   string
 	Author  string
 	ModTime time.Time
-}
+	Info    SeriesInfo
+	Cover   string // Path to the series' cover image, if it has one
+	Path    string // Path to the series' folder, or its archive for one-shots
 
-var validArchiveExtensions = map[string]struct{}{
-	".zip": {},
-	".cbz": {},
+	// Only set by overrides
+	SortTitle string
 }
 
 func ParseSeries(path string) (Series, []Entry, error) {
+	return parseSeries(context.Background(), path, LibraryOptions{})
+}
+
+func parseSeries(ctx context.Context, path string, opts LibraryOptions) (Series, []Entry, error) {
+	series, _, err := parseAll(ctx, []string{path}, nil, opts)
+	if err != nil {
+		return Series{}, nil, err
+	}
+	return series[0].Series, series[0].entries, series[0].err
+}
+
+type parsedSeries struct {
+	Series
+	entries []Entry
+	err     error
+}
+
+// Parses the series in the folders and the standalone archives, the
+// series are walked first so every entry can be parsed by the same
+// workers. The results are in the same order as the folders and files
+func parseAll(ctx context.Context, dirs, files []string, opts LibraryOptions) ([]parsedSeries, []parsedEntry, error) {
+	type walk struct {
+		paths []string
+		err   error
+	}
+	walks, err := parallel(ctx, opts.Workers, dirs, func(_ int, dir string) walk {
+		paths, err := seriesEntries(dir)
+		return walk{paths, err}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := slices.Clone(files)
+	units := make([]int, len(files))
+	for i := range units {
+		units[i] = i
+	}
+	for i, w := range walks {
+		if w.err == nil {
+			paths = append(paths, w.paths...)
+			for range w.paths {
+				units = append(units, len(files)+i)
+			}
+		}
+	}
+	pr := newProgress(opts.Progress, units, len(files)+len(dirs))
+	parsed, err := parseEntries(ctx, paths, opts, pr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	series := make([]parsedSeries, len(dirs))
+	next := len(files)
+	for i, w := range walks {
+		if w.err != nil {
+			series[i].err = w.err
+			continue
+		}
+		sr, entries, err := newSeries(dirs[i], w.paths, parsed[next:next+len(w.paths)])
+		next += len(w.paths)
+		series[i] = parsedSeries{sr, entries, err}
+	}
+	return series, parsed[:len(files)], nil
+}
+
+// Returns the paths of the series' entries in the order they're walked
+func seriesEntries(path string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			// Folders of images inside the series
+			// are treated as entries themselves
+			if p == path || !isImageDir(p) {
+				return nil
+			}
+			paths = append(paths, p)
+			return fs.SkipDir
+		} else if _, valid := archiveTypeOf(p); !valid {
+			return nil
+		}
+		paths = append(paths, p)
+		return nil
+	})
+	return paths, err
+}
+
+type parsedEntry struct {
+	Entry
+	err error
+}
+
+// Entries are parsed by the workers in parallel, their order is kept
+func parseEntries(ctx context.Context, paths []string, opts LibraryOptions, pr *progress) ([]parsedEntry, error) {
+	return parallel(ctx, opts.Workers, paths, func(i int, p string) parsedEntry {
+		e, err := parseKnownEntry(p, opts.Known)
+		pr.parsed(i, p, err)
+		return parsedEntry{e, err}
+	})
+}
+
+// How much of the library has been parsed, series are parsed
+// once all of their entries are, and standalone archives count
+// as series of their own
+type ParseProgress struct {
+	Series       int
+	TotalSeries  int
+	Entries      int
+	TotalEntries int
+	Path         string // The archive which was parsed last
+	Err          error  // Why the archive couldn't be parsed
+}
+
+type progress struct {
+	mu        sync.Mutex
+	report    func(ParseProgress)
+	units     []int // Series of each entry
+	remaining []int // Entries left to parse in each series
+	p         ParseProgress
+}
+
+func newProgress(report func(ParseProgress), units []int, n int) *progress {
+	pr := &progress{report: report, units: units, remaining: make([]int, n)}
+	for _, u := range units {
+		pr.remaining[u]++
+	}
+	pr.p.TotalSeries = n
+	pr.p.TotalEntries = len(units)
+	for _, r := range pr.remaining {
+		if r == 0 {
+			pr.p.Series++
+		}
+	}
+	if report != nil {
+		report(pr.p)
+	}
+	return pr
+}
+
+func (pr *progress) parsed(i int, path string, err error) {
+	if pr.report == nil {
+		return
+	}
+	// Reports are made while locked so they're in order
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.p.Entries++
+	if u := pr.units[i]; pr.remaining[u] > 0 {
+		pr.remaining[u]--
+		if pr.remaining[u] == 0 {
+			pr.p.Series++
+		}
+	}
+	pr.p.Path, pr.p.Err = path, err
+	pr.report(pr.p)
+}
+
+func newSeries(path string, paths []string, parsed []parsedEntry) (Series, []Entry, error) {
 	slog.Debug("Parsing series", slog.String("path", path))
 
 	stat, err := os.Stat(path)
@@ -157,57 +515,104 @@ func ParseSeries(path string) (Series, []Entry, error) {
 		return Series{}, nil, err
 	}
 
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Series{}, nil, err
+	}
 	s := Series{
 		SID:     Sha256(stat.Name()),
 		Title:   stat.Name(),
 		ModTime: time.Time{},
+		Path:    abs,
 	}
 	entries := make([]Entry, 0)
 
 	// Authors do not necessarily have to exist
-	authorFile, err := os.Open(path + "/author.txt")
+	var authors map[Role]string
+	authorFile, err := os.Open(filepath.Join(path, authorFilename))
 	if err == nil {
-		author, err := io.ReadAll(authorFile)
+		data, err := io.ReadAll(authorFile)
+		authorFile.Close()
 		if err != nil {
-			return Series{}, nil, fmt.Errorf("read author.txt")
+			return Series{}, nil, fmt.Errorf("read %s", authorFilename)
 		}
-		s.Author = strings.TrimRight(string(author), "\n")
+		authors = parseAuthorFile(string(data))
 	} else if !errors.Is(err, fs.ErrNotExist) {
 		slog.Error("Could not open author file", slog.Any("err", err))
 	}
 
-	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if d.IsDir() {
-			return nil
-		}
-		_, valid := validArchiveExtensions[filepath.Ext(p)]
-		if !valid {
-			return nil
+	var pErr ParseError
+	for i, p := range paths {
+		e, err := parsed[i].Entry, parsed[i].err
+		if t, _ := archiveTypeOf(p); err != nil && t.Format == formatPdf {
+			// PDFs which are unsupported or malformed are
+			// rejected without affecting the rest of the series
+			rel, _ := filepath.Rel(path, p)
+			pErr.Items = append(pErr.Items, ParseErrorItem{rel, err})
+			continue
+		} else if err != nil {
+			return Series{}, nil, fmt.Errorf("parse entry %s: %w", p, err)
 		}
-
-		e, err := ParseEntry(p)
+		e.SID = s.SID
+		rel, err := filepath.Rel(path, filepath.Dir(p))
 		if err != nil {
-			return fmt.Errorf("parse entry %s: %w", p, err)
+			return Series{}, nil, err
+		}
+		if rel != "." {
+			e.Folder = filepath.ToSlash(rel)
 		}
-		e.SID = s.SID
 		if e.ModTime.After(s.ModTime) {
 			s.ModTime = e.ModTime
 		}
 		entries = append(entries, e)
+	}
+	s.Info = newSeriesInfo(entries)
+	for _, e := range entries {
+		s.Author = mergeList(s.Author, e.Author)
+	}
+	s.applyAuthorFile(authors)
 
-		return nil
-	})
+	s.Cover, err = findSeriesCover(path)
 	if err != nil {
-		return Series{}, nil, err
+		return Series{}, nil, fmt.Errorf("find cover: %w", err)
+	}
+
+	// Sidecars do not necessarily have to exist either
+	sidecar, err := parseMylarSeries(filepath.Join(path, mylarFilename))
+	if err == nil {
+		sidecar.apply(&s)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		slog.Error("Could not read sidecar", slog.String("path", path), slog.Any("err", err))
+	}
+
+	if len(pErr.Items) > 0 {
+		return s, entries, &pErr
 	}
 
 	return s, entries, nil
 }
 
+// Series can have their own cover, otherwise
+// the cover of their first entry is used
+var seriesCoverNames = map[string]struct{}{
+	"cover.jpg":  {},
+	"cover.jpeg": {},
+	"cover.png":  {},
+}
+
+func findSeriesCover(path string) (string, error) {
+	items, err := os.ReadDir(path)
+	if err != nil {
+		return "", err
+	}
+	for _, item := range items {
+		if _, found := seriesCoverNames[strings.ToLower(item.Name())]; found && item.Type().IsRegular() {
+			return filepath.Abs(filepath.Join(path, item.Name()))
+		}
+	}
+	return "", nil
+}
+
 // Library
 
 type ParseErrorItem struct {
@@ -227,7 +632,27 @@ func (pe *ParseError) Error() string {
 	return "parse errors: " + strings.Join(msgs, "; ")
 }
 
-func ParseLibrary(path string) (map[Series][]Entry, error) {
+type LibraryOptions struct {
+	// Standalone archives in the library's root are grouped
+	// under a series with this title, if it's empty then each
+	// archive becomes a series of its own
+	OneShots string
+	// Entries which were parsed by a previous scan, keyed by
+	// their archive, they're reused if they haven't changed
+	Known map[string]Entry
+	// How many archives are parsed at once, at least one is
+	Workers int
+	// Called as each archive's parsed, if it's set
+	Progress func(ParseProgress)
+}
+
+func ParseLibrary(path string, opts LibraryOptions) (map[Series][]Entry, error) {
+	return ParseLibraryContext(context.Background(), path, opts)
+}
+
+// Once the context's cancelled no more archives are parsed and
+// its error is returned, instead of a partially parsed library
+func ParseLibraryContext(ctx context.Context, path string, opts LibraryOptions) (map[Series][]Entry, error) {
 	lib := make(map[Series][]Entry)
 
 	items, err := os.ReadDir(path)
@@ -235,20 +660,86 @@ func ParseLibrary(path string) (map[Series][]Entry, error) {
 		return nil, err
 	}
 
+	var dirs, files []string
+	for _, item := range items {
+		if item.IsDir() {
+			dirs = append(dirs, filepath.Join(path, item.Name()))
+		} else if _, valid := archiveTypeOf(item.Name()); valid {
+			files = append(files, filepath.Join(path, item.Name()))
+		}
+	}
+	series, parsed, err := parseAll(ctx, dirs, files, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The results are collected in the library's order
 	var pErr ParseError
+	var oneShots []Entry
+	var dir, file int
 	for _, item := range items {
 		if !item.IsDir() {
+			if _, valid := archiveTypeOf(item.Name()); !valid {
+				continue
+			}
+
+			r := parsed[file]
+			file++
+			if r.err != nil {
+				pErr.Items = append(pErr.Items, ParseErrorItem{item.Name(), r.err})
+				continue
+			}
+			oneShots = append(oneShots, r.Entry)
 			continue
 		}
 
-		series, entries, err := ParseSeries(filepath.Join(path, item.Name()))
-		if err != nil {
-			pErr.Items = append(pErr.Items, ParseErrorItem{item.Name(), err})
+		r := series[dir]
+		dir++
+		var sErr *ParseError
+		if errors.As(r.err, &sErr) {
+			// The series was parsed but some of its
+			// entries were rejected
+			for _, sItem := range sErr.Items {
+				name := filepath.Join(item.Name(), sItem.Name)
+				pErr.Items = append(pErr.Items, ParseErrorItem{name, sItem.Err})
+			}
+		} else if r.err != nil {
+			pErr.Items = append(pErr.Items, ParseErrorItem{item.Name(), r.err})
 			continue
 		}
 
-		lib[series] = entries
+		lib[r.Series] = r.entries
+	}
+
+	if opts.OneShots != "" && len(oneShots) > 0 {
+		root, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		// Folder names can't contain a slash, so the
+		// group's ID can't clash with a folder's
+		s := Series{SID: Sha256(opts.OneShots + "/"), Title: opts.OneShots, Path: root,
+			Info: newSeriesInfo(oneShots)}
+		for i := range oneShots {
+			oneShots[i].SID = s.SID
+			if oneShots[i].ModTime.After(s.ModTime) {
+				s.ModTime = oneShots[i].ModTime
+			}
+		}
+		lib[s] = oneShots
+	} else {
+		for _, e := range oneShots {
+			// The series is identified by the archive's
+			// name with its extension, so it can't clash
+			// with a folder or another archive's series
+			s := Series{SID: Sha256(filepath.Base(e.Archive)), Title: e.Title, Author: e.Author, ModTime: e.ModTime,
+				Path: e.Archive, Info: newSeriesInfo([]Entry{e})}
+			e.SID = s.SID
+			lib[s] = []Entry{e}
+		}
 	}
+	uniqueTitles(lib)
+
 	if len(pErr.Items) > 0 {
 		// We return what we've succesfully managed to parse
 		// instead of only returning an empty library
@@ -258,6 +749,31 @@ func ParseLibrary(path string) (map[Series][]Entry, error) {
 	return lib, nil
 }
 
+// Series titles must be unique, e.g. a one-shot can have
+// the same title as a folder, so all but the first series
+// with a title, in the natural order of their paths, have
+// their path's name appended to it
+func uniqueTitles(lib map[Series][]Entry) {
+	byTitle := make(map[string][]Series)
+	for sr := range lib {
+		byTitle[sr.Title] = append(byTitle[sr.Title], sr)
+	}
+	for _, group := range byTitle {
+		if len(group) == 1 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			return natural.Less(group[i].Path, group[j].Path)
+		})
+		for _, sr := range group[1:] {
+			entries := lib[sr]
+			delete(lib, sr)
+			sr.Title = fmt.Sprintf("%s (%s)", sr.Title, filepath.Base(sr.Path))
+			lib[sr] = entries
+		}
+	}
+}
+
 // Hashing / Encoding
 
 func Sha256(s string) string {
@@ -1,7 +1,12 @@
 package tanuki
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -44,6 +49,168 @@ func TestParsing_ParseEntry(t *testing.T) {
 		e.SID = "wNgocaIzfIjmFcxC-5I3S5pEpjRKjDY4nRxg9Ko-z7k"
 		require.Equal(t, amanoEntries[0], e)
 	})
+
+	for _, path := range []string{
+		"tests/lib-cbr/Amano/Amano Megumi wa Suki Darake! v01.cbr",
+		"tests/lib-cb7/Amano/Amano Megumi wa Suki Darake! v01.cb7",
+		"tests/lib-cbt/Amano/Amano Megumi wa Suki Darake! v01.cbt",
+		"tests/lib-tgz/Amano/Amano Megumi wa Suki Darake! v01.tar.gz",
+	} {
+		t.Run("Amano ("+archiveExt(path)+")", func(t *testing.T) {
+			e, err := ParseEntry(path)
+			require.NoError(t, err)
+
+			// The archive contains the same pages as
+			// the ZIP archive, so they should be parsed
+			// and sorted identically
+			require.Equal(t, amanoEntries[0].EID, e.EID)
+			require.Equal(t, amanoEntries[0].Title, e.Title)
+			require.Len(t, e.Pages, len(amanoEntries[0].Pages))
+			for i, p := range e.Pages {
+				require.Equal(t, amanoEntries[0].Pages[i].Path, p.Path)
+				require.Equal(t, amanoEntries[0].Pages[i].Mime, p.Mime)
+				require.Equal(t, amanoEntries[0].Pages[i].Width, p.Width)
+				require.Equal(t, amanoEntries[0].Pages[i].Height, p.Height)
+				require.Equal(t, amanoEntries[0].Pages[i].Bytes, p.Bytes)
+
+				// Only uncompressed tarballs record where
+				// their pages are located
+				if filepath.Ext(path) == ".cbt" {
+					require.Positive(t, p.Offset)
+					require.Positive(t, p.Size)
+				} else {
+					require.Zero(t, p.Offset)
+					require.Zero(t, p.Size)
+				}
+			}
+		})
+	}
+}
+
+func TestParsing_ParseEntry_Pdf(t *testing.T) {
+	t.Run("image-only", func(t *testing.T) {
+		// One PDF uses an xref table and the other
+		// uses an xref stream and object streams
+		for i, path := range []string{
+			"tests/lib-pdf/Akira/Volume 01.pdf",
+			"tests/lib-pdf/Akira/Volume 02.pdf",
+		} {
+			e, err := ParseEntry(path)
+			require.NoError(t, err)
+			require.Equal(t, akiraEntries[i].EID, e.EID)
+			require.Equal(t, akiraEntries[i].Title, e.Title)
+			require.Len(t, e.Pages, len(akiraEntries[i].Pages))
+			for j, p := range e.Pages {
+				require.Equal(t, strconv.Itoa(j+1)+".jpg", p.Path)
+				require.Equal(t, "image/jpeg", p.Mime)
+				require.Positive(t, p.Offset)
+				require.Positive(t, p.Size)
+			}
+		}
+	})
+
+	t.Run("not image-only", func(t *testing.T) {
+		_, err := ParseEntry("tests/lib-pdf/Akira/Extras.pdf")
+		require.ErrorIs(t, err, errUnsupportedPdf)
+	})
+}
+
+func TestParsing_ParseEntry_Epub(t *testing.T) {
+	e, err := ParseEntry("tests/lib-epub/Amano/Amano Megumi wa Suki Darake! v01.epub")
+	require.NoError(t, err)
+
+	// The title and author come from the package document
+	// but the EID is still based on the filename
+	require.Equal(t, amanoEntries[0].EID, e.EID)
+	require.Equal(t, "Amano Megumi wa Suki Darake! Vol. 1", e.Title)
+	require.Equal(t, "Nekoguchi", e.Author)
+
+	// Images are named in reverse, so the pages are only
+	// correct if they follow the spine's order, the table
+	// of contents shouldn't be included since it has no image
+	n := len(amanoEntries[0].Pages)
+	require.Len(t, e.Pages, n)
+	for i, p := range e.Pages {
+		expected := fmt.Sprintf("OEBPS/images/page %02d%s", n-i, filepath.Ext(amanoEntries[0].Pages[i].Path))
+		require.Equal(t, expected, p.Path)
+		require.Equal(t, amanoEntries[0].Pages[i].Mime, p.Mime)
+	}
+}
+
+func TestParsing_ParseEntry_Folder(t *testing.T) {
+	e, err := ParseEntry("tests/lib-dir/Amano/Vol.01 Ch.0001 - A")
+	require.NoError(t, err)
+	require.Equal(t, Sha256("Vol.01 Ch.0001 - A"), e.EID)
+	require.Equal(t, "Vol.01 Ch.0001 - A", e.Title)
+
+	// Only images are pages, so notes.txt is ignored
+	require.Len(t, e.Pages, 11)
+	var size int64
+	for i, p := range e.Pages {
+		require.Equal(t, amanoEntries[0].Pages[i].Path, "Vol.01 Ch.0001 - A/"+p.Path)
+		require.Equal(t, amanoEntries[0].Pages[i].Mime, p.Mime)
+
+		stat, err := os.Stat(filepath.Join(e.Archive, p.Path))
+		require.NoError(t, err)
+		size += stat.Size()
+	}
+	require.Equal(t, size, e.Filesize)
+}
+
+func TestParsing_ParseEntry_Known(t *testing.T) {
+	for _, path := range []string{"tests/lib/Akira/Volume 01.zip", "tests/lib-dir/Amano/Vol.01 Ch.0001 - A"} {
+		e, err := ParseEntry(path)
+		require.NoError(t, err)
+
+		// Unchanged entries are reused without their IDs or folder
+		known := e
+		known.EID, known.SID, known.Folder, known.Title = "a", "b", "c", "Known"
+		reused, err := parseKnownEntry(path, map[string]Entry{e.Archive: known})
+		require.NoError(t, err)
+		require.Equal(t, e.EID, reused.EID)
+		require.Empty(t, reused.SID)
+		require.Empty(t, reused.Folder)
+		require.Equal(t, "Known", reused.Title)
+
+		// Changed entries are parsed again
+		known.ModTime = known.ModTime.Add(-time.Hour)
+		parsed, err := parseKnownEntry(path, map[string]Entry{e.Archive: known})
+		require.NoError(t, err)
+		require.Equal(t, e, parsed)
+	}
+}
+
+func TestParsing_ParseEntry_ComicInfo(t *testing.T) {
+	e, err := ParseEntry("tests/lib-comicinfo/Amano/Amano Megumi wa Suki Darake! v01.cbz")
+	require.NoError(t, err)
+
+	// ComicInfo.xml isn't a page
+	require.Len(t, e.Pages, len(amanoEntries[0].Pages))
+	require.Equal(t, amanoEntries[0].Title, e.Title)
+	require.Equal(t, "Nekoguchi", e.Author)
+	require.Equal(t, ComicInfo{
+		Series:      "Amano Megumi wa Suki Darake!",
+		Number:      "1",
+		Volume:      1,
+		Title:       "Amano Megumi Is Full of Openings!",
+		Summary:     "Makoto has to study hard to get into a top university.",
+		Writer:      "Nekoguchi",
+		Penciller:   "Nekoguchi Art",
+		Publisher:   "Shogakukan",
+		Year:        2016,
+		LanguageISO: "ja",
+		AgeRating:   "Teen",
+		Manga:       "YesAndRightToLeft",
+		Genre:       "Comedy, Romance,School Life",
+		Pages: []ComicInfoPage{
+			{Image: 0, Type: "InnerCover"},
+			{Image: 1, Type: "FrontCover"},
+			{Image: 2},
+		},
+	}, e.Info)
+
+	// The page marked as the front cover is used
+	require.Equal(t, 1, e.CoverPage)
 }
 
 func TestParsing_ParseSeries(t *testing.T) {
@@ -72,14 +239,223 @@ func TestParsing_ParseSeries(t *testing.T) {
 		s, e, err := ParseSeries("tests/lib-cbz/Amano")
 		require.NoError(t, err)
 		require.Len(t, e, 1)
-		require.Equal(t, amanoSeries, s)
+		expected := amanoSeries
+		expected.Path = strings.Replace(expected.Path, "/lib/", "/lib-cbz/", 1)
+		require.Equal(t, expected, s)
+	})
+
+	t.Run("Amano (ComicInfo)", func(t *testing.T) {
+		s, _, err := ParseSeries("tests/lib-comicinfo/Amano")
+		require.NoError(t, err)
+		require.Equal(t, SeriesInfo{
+			Publisher:   "Shogakukan",
+			Year:        2016,
+			LanguageISO: "ja",
+			AgeRating:   "Teen",
+			Manga:       "YesAndRightToLeft",
+			Genre:       "Comedy, Romance, School Life",
+			Artist:      "Nekoguchi Art",
+		}, s.Info)
+		require.Equal(t, []Credit{
+			{Name: "Nekoguchi", Role: RoleWriter},
+			{Name: "Nekoguchi Art", Role: RoleArtist},
+		}, s.Credits())
 	})
+
+	t.Run("Amano (series.json)", func(t *testing.T) {
+		s, e, err := ParseSeries("tests/lib-mylar/Amano")
+		require.NoError(t, err)
+		require.Len(t, e, 1)
+
+		// The SID and title still come from the folder's name
+		require.Equal(t, amanoSeries.SID, s.SID)
+		require.Equal(t, amanoSeries.Title, s.Title)
+		require.Equal(t, amanoSeries.Author, s.Author)
+		require.Equal(t, SeriesInfo{
+			Publisher:   "Shogakukan",
+			Year:        2016,
+			Name:        "Amano Megumi wa Suki Darake!",
+			Description: "Makoto has to study hard to get into a top university, but his childhood friend has other plans.",
+			Status:      "Ended",
+			ComicID:     "97341",
+		}, s.Info)
+
+		// It's persisted with the rest of the series
+		st := mustOpenStoreMem(t)
+		defer mustCloseStore(t, st)
+		require.NoError(t, st.PopulateCatalog(map[Series][]Entry{s: e}))
+		stored, err := st.GetSeries(s.SID)
+		require.NoError(t, err)
+		require.Equal(t, s.Info, stored.Info)
+	})
+
+	t.Run("Amano (cover)", func(t *testing.T) {
+		s, e, err := ParseSeries("tests/lib-cover/Amano")
+		require.NoError(t, err)
+		require.Len(t, e, 1)
+
+		expected, err := filepath.Abs("tests/lib-cover/Amano/Cover.jpg")
+		require.NoError(t, err)
+		require.Equal(t, expected, s.Cover)
+	})
+
+	t.Run("Amano (folders)", func(t *testing.T) {
+		s, e, err := ParseSeries("tests/lib-dir/Amano")
+		require.NoError(t, err)
+		require.Len(t, e, 2)
+		require.Equal(t, amanoSeries.SID, s.SID)
+		require.Equal(t, amanoSeries.Author, s.Author)
+		require.Equal(t, "Vol.01 Ch.0001 - A", e[0].Title)
+		require.Equal(t, "Vol.01 Ch.0002 - B", e[1].Title)
+		require.Len(t, e[1].Pages, 11)
+	})
+
+	for _, path := range []string{
+		"tests/lib-cbr/Amano",
+		"tests/lib-cb7/Amano",
+		"tests/lib-cbt/Amano",
+		"tests/lib-tgz/Amano",
+	} {
+		t.Run("Amano ("+filepath.Dir(path)+")", func(t *testing.T) {
+			s, e, err := ParseSeries(path)
+			require.NoError(t, err)
+			require.Len(t, e, 1)
+			require.Equal(t, amanoSeries.SID, s.SID)
+			require.Equal(t, amanoSeries.Author, s.Author)
+		})
+	}
+}
+
+func TestParsing_ParseSeries_Pdf(t *testing.T) {
+	// The unsupported PDF is rejected but the
+	// rest of the series is still parsed
+	s, e, err := ParseSeries("tests/lib-pdf/Akira")
+	var pErr *ParseError
+	require.ErrorAs(t, err, &pErr)
+	require.Len(t, pErr.Items, 1)
+	require.Equal(t, "Extras.pdf", pErr.Items[0].Name)
+	require.ErrorIs(t, pErr.Items[0].Err, errUnsupportedPdf)
+
+	require.Equal(t, akiraSeries.SID, s.SID)
+	require.Len(t, e, 2)
+	require.Equal(t, akiraEntries[0].Title, e[0].Title)
+	require.Equal(t, akiraEntries[1].Title, e[1].Title)
+
+	// So are PDFs which are malformed
+	dir := t.TempDir()
+	require.NoError(t, os.CopyFS(dir, os.DirFS("tests/lib-pdf/Akira")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Extras.pdf"), []byte("%PDF-1.4\nstartxref\n0\n%%EOF"), 0o644))
+	_, e, err = ParseSeries(dir)
+	require.ErrorAs(t, err, &pErr)
+	require.Len(t, pErr.Items, 1)
+	require.Equal(t, "Extras.pdf", pErr.Items[0].Name)
+	require.Len(t, e, 2)
 }
 
 func TestParsing_ParseLibrary(t *testing.T) {
-	lib, err := ParseLibrary("tests/lib")
+	lib, err := ParseLibrary("tests/lib", LibraryOptions{})
 	require.NoError(t, err)
 	require.Equal(t, parsedLib, lib)
+
+	t.Run("rejected entries", func(t *testing.T) {
+		lib, err := ParseLibrary("tests/lib-pdf", LibraryOptions{})
+		var pErr *ParseError
+		require.ErrorAs(t, err, &pErr)
+		require.Len(t, pErr.Items, 1)
+		require.Equal(t, "Akira/Extras.pdf", pErr.Items[0].Name)
+		require.Len(t, lib, 1)
+	})
+
+	t.Run("standalone entries", func(t *testing.T) {
+		lib, err := ParseLibrary("tests/lib-oneshots", LibraryOptions{})
+		require.NoError(t, err)
+		require.Len(t, lib, 3)
+
+		// Each archive becomes a series named after itself
+		for series, entries := range lib {
+			if series.Title == "Amano" {
+				continue
+			}
+			require.Contains(t, []string{"Akira Volume 01", "Akira Volume 02"}, series.Title)
+			require.Len(t, entries, 1)
+			require.Equal(t, Sha256(filepath.Base(entries[0].Archive)), series.SID)
+			require.Equal(t, series.SID, entries[0].SID)
+			require.Equal(t, series.Title, entries[0].Title)
+			require.Equal(t, entries[0].ModTime, series.ModTime)
+		}
+	})
+
+	t.Run("clashing standalone entries", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.CopyFS(dir, os.DirFS("tests/lib-oneshots")))
+		archive := filepath.Join(dir, "Akira Volume 01.zip")
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "Akira Volume 01"), 0o755))
+		require.NoError(t, os.Link(archive, filepath.Join(dir, "Akira Volume 01", "v1.zip")))
+		require.NoError(t, os.Link(archive, filepath.Join(dir, "Akira Volume 01.cbz")))
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "One-shots"), 0o755))
+		require.NoError(t, os.Link(archive, filepath.Join(dir, "One-shots", "v1.zip")))
+
+		// Neither the folders nor the archives share an ID or a title
+		for _, oneShots := range []string{"", "One-shots"} {
+			lib, err := ParseLibrary(dir, LibraryOptions{OneShots: oneShots})
+			require.NoError(t, err)
+			sids := make(map[string]struct{})
+			titles := make(map[string]struct{})
+			for series := range lib {
+				sids[series.SID] = struct{}{}
+				titles[series.Title] = struct{}{}
+			}
+			require.Len(t, sids, len(lib))
+			require.Len(t, titles, len(lib))
+
+			s := mustOpenStoreMem(t)
+			require.NoError(t, s.PopulateCatalog(lib))
+			ctl, err := s.GetCatalog()
+			require.NoError(t, err)
+			require.Len(t, ctl, len(lib))
+			mustCloseStore(t, s)
+		}
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		for _, path := range []string{"tests/lib", "tests/lib-nested", "tests/lib-oneshots"} {
+			expected, err := ParseLibrary(path, LibraryOptions{OneShots: "One-shots"})
+			require.NoError(t, err)
+			lib, err := ParseLibrary(path, LibraryOptions{OneShots: "One-shots", Workers: 4})
+			require.NoError(t, err)
+			require.Equal(t, expected, lib)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		lib, err := ParseLibraryContext(ctx, "tests/lib", LibraryOptions{Workers: 4})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Nil(t, lib)
+	})
+
+	t.Run("grouped standalone entries", func(t *testing.T) {
+		lib, err := ParseLibrary("tests/lib-oneshots", LibraryOptions{OneShots: "One-shots"})
+		require.NoError(t, err)
+		require.Len(t, lib, 2)
+
+		var s Series
+		var es []Entry
+		for series, entries := range lib {
+			if series.Title == "One-shots" {
+				s, es = series, entries
+			}
+		}
+		require.Equal(t, Sha256("One-shots/"), s.SID)
+		require.Len(t, es, 2)
+		require.Equal(t, "Akira Volume 01", es[0].Title)
+		require.Equal(t, "Akira Volume 02", es[1].Title)
+		for _, e := range es {
+			require.Equal(t, s.SID, e.SID)
+			require.False(t, e.ModTime.After(s.ModTime))
+		}
+	})
 }
 
 // Parsed data
@@ -103,41 +479,46 @@ var centurySeries = Series{
 	Title:   "20th Century Boys",
 	Author:  "Naoki Urusawa",
 	ModTime: parseTime("2022-08-11T16:53:23.8437325+01:00"),
+	Path:    folderPath + "/20th Century Boys",
 }
 
 var centuryEntries = []Entry{
 	{
-		EID:      "O_wmlZTvZJIo6adLqwDwQu_JHVrMb77jGjgugNQjiP4",
-		SID:      "PvHfuhL24GD6jo-PKLbPj_KvRikLn2WjCw_gOaXKRyI",
-		Title:    "v1",
-		ModTime:  parseTime("2022-08-11T16:53:23.8317325+01:00"),
-		Archive:  folderPath + "/20th Century Boys/v1.zip",
-		Filesize: 27143,
+		EID:         "O_wmlZTvZJIo6adLqwDwQu_JHVrMb77jGjgugNQjiP4",
+		SID:         "PvHfuhL24GD6jo-PKLbPj_KvRikLn2WjCw_gOaXKRyI",
+		Title:       "v1",
+		ModTime:     parseTime("2022-08-11T16:53:23.8317325+01:00"),
+		Archive:     folderPath + "/20th Century Boys/v1.zip",
+		Filesize:    27143,
+		Fingerprint: "Mo1SKWdyPE4ZoTrV5tJPNjV86k4FqmvBv3rjy_09T4A",
+		Volume:      Span{Start: 1, End: 1, Valid: true},
 		Pages: Pages{
-			{Path: "0000.jpg", Mime: "image/jpeg"},
-			{Path: "20th Century Boys v01 (001).png", Mime: "image/png"},
-			{Path: "20th Century Boys v01 (002).png", Mime: "image/png"},
-			{Path: "20th Century Boys v01 (003).png", Mime: "image/png"},
-			{Path: "20th Century Boys v01 (004).png", Mime: "image/png"},
-			{Path: "20th Century Boys v01 (005).png", Mime: "image/png"},
-			{Path: "20th Century Boys v01 (006).png", Mime: "image/png"},
+			{Path: "0000.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 3240},
+			{Path: "20th Century Boys v01 (001).png", Mime: "image/png", Width: 64, Height: 42, Bytes: 5520, Wide: true},
+			{Path: "20th Century Boys v01 (002).png", Mime: "image/png", Width: 28, Height: 64, Bytes: 3915},
+			{Path: "20th Century Boys v01 (003).png", Mime: "image/png", Width: 44, Height: 64, Bytes: 5863},
+			{Path: "20th Century Boys v01 (004).png", Mime: "image/png", Width: 64, Height: 46, Bytes: 2719, Wide: true},
+			{Path: "20th Century Boys v01 (005).png", Mime: "image/png", Width: 44, Height: 64, Bytes: 2227},
+			{Path: "20th Century Boys v01 (006).png", Mime: "image/png", Width: 41, Height: 64, Bytes: 2507},
 		},
 	},
 	{
-		EID:      "-wTctpcOTD0Yc95R_VpQ17tGszgxE2AmZcNQ7EC1-ZA",
-		SID:      "PvHfuhL24GD6jo-PKLbPj_KvRikLn2WjCw_gOaXKRyI",
-		Title:    "v2",
-		ModTime:  parseTime("2022-08-11T16:53:23.8437325+01:00"),
-		Archive:  folderPath + "/20th Century Boys/v2.zip",
-		Filesize: 27704,
+		EID:         "-wTctpcOTD0Yc95R_VpQ17tGszgxE2AmZcNQ7EC1-ZA",
+		SID:         "PvHfuhL24GD6jo-PKLbPj_KvRikLn2WjCw_gOaXKRyI",
+		Title:       "v2",
+		ModTime:     parseTime("2022-08-11T16:53:23.8437325+01:00"),
+		Archive:     folderPath + "/20th Century Boys/v2.zip",
+		Filesize:    27704,
+		Fingerprint: "ydPj725g9BVRnkY0OfdtxgsmJxPglsUBWjdO56ilvWA",
+		Volume:      Span{Start: 2, End: 2, Valid: true},
 		Pages: Pages{
-			{Path: "0000.jpg", Mime: "image/jpeg"},
-			{Path: "20th Century Boys v02 (001).png", Mime: "image/png"},
-			{Path: "20th Century Boys v02 (002).png", Mime: "image/png"},
-			{Path: "20th Century Boys v02 (003).png", Mime: "image/png"},
-			{Path: "20th Century Boys v02 (004).png", Mime: "image/png"},
-			{Path: "20th Century Boys v02 (005).png", Mime: "image/png"},
-			{Path: "20th Century Boys v02 (006).png", Mime: "image/png"},
+			{Path: "0000.jpg", Mime: "image/jpeg", Width: 43, Height: 64, Bytes: 3202},
+			{Path: "20th Century Boys v02 (001).png", Mime: "image/png", Width: 64, Height: 42, Bytes: 5794, Wide: true},
+			{Path: "20th Century Boys v02 (002).png", Mime: "image/png", Width: 29, Height: 64, Bytes: 3708},
+			{Path: "20th Century Boys v02 (003).png", Mime: "image/png", Width: 45, Height: 64, Bytes: 6341},
+			{Path: "20th Century Boys v02 (004).png", Mime: "image/png", Width: 64, Height: 46, Bytes: 2833, Wide: true},
+			{Path: "20th Century Boys v02 (005).png", Mime: "image/png", Width: 45, Height: 64, Bytes: 2297},
+			{Path: "20th Century Boys v02 (006).png", Mime: "image/png", Width: 45, Height: 64, Bytes: 2379},
 		},
 	},
 }
@@ -147,52 +528,57 @@ var akiraSeries = Series{
 	Title:   "Akira",
 	Author:  "Katsuhiro Otomo",
 	ModTime: parseTime("2022-08-11T16:53:23.8677336+01:00"),
+	Path:    folderPath + "/Akira",
 }
 
 var akiraEntries = []Entry{
 	{
-		EID:      "1f2Xo_TQk-nS-9I9QsRm3zVNawdW6HlOUYJsV22wENk",
-		SID:      "rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c",
-		Title:    "Volume 01",
-		ModTime:  parseTime("2022-08-11T16:53:23.856733+01:00"),
-		Archive:  folderPath + "/Akira/Volume 01.zip",
-		Filesize: 26881,
+		EID:         "1f2Xo_TQk-nS-9I9QsRm3zVNawdW6HlOUYJsV22wENk",
+		SID:         "rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c",
+		Title:       "Volume 01",
+		ModTime:     parseTime("2022-08-11T16:53:23.856733+01:00"),
+		Archive:     folderPath + "/Akira/Volume 01.zip",
+		Filesize:    26881,
+		Fingerprint: "qiLTwq4cWCVIMwaEmgn66f731shxLR65oV0mfFl-8wA",
+		Volume:      Span{Start: 1, End: 1, Valid: true},
 		Pages: Pages{
-			{Path: "akira_1_c001.jpg", Mime: "image/jpeg"},
-			{Path: "akira_1_ic01.jpg", Mime: "image/jpeg"},
-			{Path: "akira_1_ic02-ic03.jpg", Mime: "image/jpeg"},
-			{Path: "akira_1_ic04.jpg", Mime: "image/jpeg"},
-			{Path: "akira_1_ic05.jpg", Mime: "image/jpeg"},
-			{Path: "akira_1_p001.jpg", Mime: "image/jpeg"},
-			{Path: "akira_1_p002-p003.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_1_p004-p005.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_1_p006-p007.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_1_p008.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_1_p009.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_1_p010.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_1_p011.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_1_p356-p357.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_1_rc01.jpg", Mime: "image/jpeg"},
+			{Path: "akira_1_c001.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 2477},
+			{Path: "akira_1_ic01.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 813},
+			{Path: "akira_1_ic02-ic03.jpg", Mime: "image/jpeg", Width: 64, Height: 48, Bytes: 1356, Wide: true},
+			{Path: "akira_1_ic04.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 1180},
+			{Path: "akira_1_ic05.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 1459},
+			{Path: "akira_1_p001.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 1573},
+			{Path: "akira_1_p002-p003.jpg", Mime: "image/jpeg", Width: 64, Height: 47, Bytes: 2160, Wide: true},
+			{Path: "Akira_1_p004-p005.jpg", Mime: "image/jpeg", Width: 64, Height: 47, Bytes: 2172, Wide: true},
+			{Path: "Akira_1_p006-p007.jpg", Mime: "image/jpeg", Width: 64, Height: 46, Bytes: 1862, Wide: true},
+			{Path: "Akira_1_p008.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 2120},
+			{Path: "Akira_1_p009.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 1109},
+			{Path: "Akira_1_p010.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 1781},
+			{Path: "Akira_1_p011.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 1804},
+			{Path: "Akira_1_p356-p357.jpg", Mime: "image/jpeg", Width: 64, Height: 46, Bytes: 787, Wide: true},
+			{Path: "Akira_1_rc01.jpg", Mime: "image/jpeg", Width: 50, Height: 64, Bytes: 2511},
 		},
 	},
 	{
-		EID:      "ntnxQLqcSL5bQDAnFaRJKCqLMTjPtdqCEQZ1vipuw_o",
-		SID:      "rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c",
-		Title:    "Volume 02",
-		ModTime:  parseTime("2022-08-11T16:53:23.8677336+01:00"),
-		Archive:  folderPath + "/Akira/Volume 02.zip",
-		Filesize: 18690,
+		EID:         "ntnxQLqcSL5bQDAnFaRJKCqLMTjPtdqCEQZ1vipuw_o",
+		SID:         "rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c",
+		Title:       "Volume 02",
+		ModTime:     parseTime("2022-08-11T16:53:23.8677336+01:00"),
+		Archive:     folderPath + "/Akira/Volume 02.zip",
+		Filesize:    18690,
+		Fingerprint: "c6BpsFPZ0TRjZpkr07fcuQUU0iT4m95oE3vhMWFjH0s",
+		Volume:      Span{Start: 2, End: 2, Valid: true},
 		Pages: Pages{
-			{Path: "Akira_2_c001.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_2_ic01.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_2_ic02-ic03.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_2_ic04-ic05.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_2_p001-p002.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_2_p003.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_2_p004.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_2_p005.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_2_p006.jpg", Mime: "image/jpeg"},
-			{Path: "Akira_2_rc01.jpg", Mime: "image/jpeg"},
+			{Path: "Akira_2_c001.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 2422},
+			{Path: "Akira_2_ic01.jpg", Mime: "image/jpeg", Width: 43, Height: 64, Bytes: 764},
+			{Path: "Akira_2_ic02-ic03.jpg", Mime: "image/jpeg", Width: 64, Height: 47, Bytes: 1208, Wide: true},
+			{Path: "Akira_2_ic04-ic05.jpg", Mime: "image/jpeg", Width: 64, Height: 47, Bytes: 1514, Wide: true},
+			{Path: "Akira_2_p001-p002.jpg", Mime: "image/jpeg", Width: 64, Height: 46, Bytes: 2104, Wide: true},
+			{Path: "Akira_2_p003.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 2029},
+			{Path: "Akira_2_p004.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 1613},
+			{Path: "Akira_2_p005.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 1623},
+			{Path: "Akira_2_p006.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 1750},
+			{Path: "Akira_2_rc01.jpg", Mime: "image/jpeg", Width: 50, Height: 64, Bytes: 2516},
 		},
 	},
 }
@@ -202,39 +588,42 @@ var amanoSeries = Series{
 	Title:   "Amano",
 	Author:  "Nekoguchi",
 	ModTime: parseTime("2022-08-11T16:53:23.888737+01:00"),
+	Path:    folderPath + "/Amano",
 }
 
 var amanoEntries = []Entry{
 	{
-		EID:      "r60ZPxCs2SaWHRLpogVWVibDPnkquh8REuYXO4mTYTg",
-		SID:      "wNgocaIzfIjmFcxC-5I3S5pEpjRKjDY4nRxg9Ko-z7k",
-		Title:    "Amano Megumi wa Suki Darake! v01",
-		ModTime:  parseTime("2022-08-11T16:53:23.888737+01:00"),
-		Archive:  folderPath + "/Amano/Amano Megumi wa Suki Darake! v01.zip",
-		Filesize: 118344,
+		EID:         "r60ZPxCs2SaWHRLpogVWVibDPnkquh8REuYXO4mTYTg",
+		SID:         "wNgocaIzfIjmFcxC-5I3S5pEpjRKjDY4nRxg9Ko-z7k",
+		Title:       "Amano Megumi wa Suki Darake! v01",
+		ModTime:     parseTime("2022-08-11T16:53:23.888737+01:00"),
+		Archive:     folderPath + "/Amano/Amano Megumi wa Suki Darake! v01.zip",
+		Filesize:    118344,
+		Fingerprint: "7OjPNcwwYhbsjzKJbH1OHbmybSrVKYDBIT3BMiVuy44",
+		Volume:      Span{Start: 1, End: 1, Valid: true},
 		Pages: Pages{
-			{Path: "Vol.01 Ch.0001 - A/001.jpg", Mime: "image/jpeg"},
-			{Path: "Vol.01 Ch.0001 - A/002.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0001 - A/003.jpg", Mime: "image/jpeg"},
-			{Path: "Vol.01 Ch.0001 - A/004.jpg", Mime: "image/jpeg"},
-			{Path: "Vol.01 Ch.0001 - A/005.jpg", Mime: "image/jpeg"},
-			{Path: "Vol.01 Ch.0001 - A/006.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0001 - A/007.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0001 - A/008.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0001 - A/009.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0001 - A/010.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0001 - A/011.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/001.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/002.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/003.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/004.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/005.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/006.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/007.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/008.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/009.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/010.png", Mime: "image/png"},
-			{Path: "Vol.01 Ch.0002 - B/011.png", Mime: "image/png"},
+			{Path: "Vol.01 Ch.0001 - A/001.jpg", Mime: "image/jpeg", Width: 41, Height: 64, Bytes: 3860},
+			{Path: "Vol.01 Ch.0001 - A/002.png", Mime: "image/png", Width: 41, Height: 64, Bytes: 4831},
+			{Path: "Vol.01 Ch.0001 - A/003.jpg", Mime: "image/jpeg", Width: 45, Height: 64, Bytes: 5120},
+			{Path: "Vol.01 Ch.0001 - A/004.jpg", Mime: "image/jpeg", Width: 64, Height: 46, Bytes: 5480, Wide: true},
+			{Path: "Vol.01 Ch.0001 - A/005.jpg", Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 4836},
+			{Path: "Vol.01 Ch.0001 - A/006.png", Mime: "image/png", Width: 43, Height: 64, Bytes: 2668},
+			{Path: "Vol.01 Ch.0001 - A/007.png", Mime: "image/png", Width: 44, Height: 64, Bytes: 6256},
+			{Path: "Vol.01 Ch.0001 - A/008.png", Mime: "image/png", Width: 44, Height: 64, Bytes: 2579},
+			{Path: "Vol.01 Ch.0001 - A/009.png", Mime: "image/png", Width: 44, Height: 64, Bytes: 6535},
+			{Path: "Vol.01 Ch.0001 - A/010.png", Mime: "image/png", Width: 44, Height: 64, Bytes: 6239},
+			{Path: "Vol.01 Ch.0001 - A/011.png", Mime: "image/png", Width: 44, Height: 64, Bytes: 6341},
+			{Path: "Vol.01 Ch.0002 - B/001.png", Mime: "image/png", Width: 44, Height: 64, Bytes: 4525},
+			{Path: "Vol.01 Ch.0002 - B/002.png", Mime: "image/png", Width: 43, Height: 64, Bytes: 6455},
+			{Path: "Vol.01 Ch.0002 - B/003.png", Mime: "image/png", Width: 44, Height: 64, Bytes: 6317},
+			{Path: "Vol.01 Ch.0002 - B/004.png", Mime: "image/png", Width: 43, Height: 64, Bytes: 6063},
+			{Path: "Vol.01 Ch.0002 - B/005.png", Mime: "image/png", Width: 44, Height: 64, Bytes: 5806},
+			{Path: "Vol.01 Ch.0002 - B/006.png", Mime: "image/png", Width: 43, Height: 64, Bytes: 6063},
+			{Path: "Vol.01 Ch.0002 - B/007.png", Mime: "image/png", Width: 43, Height: 64, Bytes: 5960},
+			{Path: "Vol.01 Ch.0002 - B/008.png", Mime: "image/png", Width: 43, Height: 64, Bytes: 6301},
+			{Path: "Vol.01 Ch.0002 - B/009.png", Mime: "image/png", Width: 43, Height: 64, Bytes: 3467},
+			{Path: "Vol.01 Ch.0002 - B/010.png", Mime: "image/png", Width: 43, Height: 64, Bytes: 5660},
+			{Path: "Vol.01 Ch.0002 - B/011.png", Mime: "image/png", Width: 44, Height: 64, Bytes: 6213},
 		},
 	},
 }
@@ -0,0 +1,315 @@
+package tanuki
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migrations
+
+// Each migration upgrades the schema by one version, the version
+// is the migration's (1-based) index. Migrations must never be
+// edited or reordered once released, changes to the schema must
+// be made by appending a new migration
+var migrations = []migration{
+	{"create tables", execStmts(
+		`CREATE TABLE IF NOT EXISTS users (
+			name TEXT PRIMARY KEY UNIQUE,
+			pass TEXT NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS series (
+			sid       TEXT     PRIMARY KEY UNIQUE,
+			title     TEXT     NOT NULL    UNIQUE,
+			author    TEXT,
+			mod_time  DATETIME NOT NULL,
+			position  INTEGER  NOT NULL,
+		    missing   INTEGER  NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS entries (
+			eid       TEXT     NOT NULL,
+			sid       TEXT     NOT NULL,
+			title     TEXT     NOT NULL,
+			mod_time  DATETIME NOT NULL,
+			archive   TEXT     NOT NULL,
+			pages     TEXT     NOT NULL,
+			filesize  INTEGER  NOT NULL,
+			position  INTEGER  NOT NULL,
+		    missing   INTEGER  NOT NULL,
+
+			-- Relationships
+			PRIMARY KEY (sid, eid),
+			FOREIGN KEY (sid) 
+		    	REFERENCES series (sid)
+                	ON UPDATE CASCADE 
+                	ON DELETE CASCADE 
+			);`,
+		`CREATE TABLE IF NOT EXISTS thumbnails (
+			eid       TEXT     NOT NULL,
+			sid       TEXT     NOT NULL,
+			mod_time  DATETIME NOT NULL,
+			data      BLOB     NOT NULL,
+
+			-- Relationships
+			PRIMARY KEY (sid, eid),
+			FOREIGN KEY (sid, eid)
+				REFERENCES entries (sid, eid)
+                	ON UPDATE CASCADE 
+                	ON DELETE CASCADE
+			);`,
+	)},
+	// Versions before migrations existed added some of these
+	// columns themselves, so only the missing ones are added
+	{"add metadata", addColumns(
+		column{"series", "info", `TEXT NOT NULL DEFAULT '{}'`},
+		column{"entries", "author", `TEXT NOT NULL DEFAULT ''`},
+		column{"entries", "info", `TEXT NOT NULL DEFAULT '{}'`},
+		column{"entries", "volume", `TEXT`},
+		column{"entries", "chapter", `TEXT`},
+	)},
+	{"add overrides", execStmts(
+		// Overrides must outlive the rows they override, e.g.
+		// if a series is temporarily removed from the library,
+		// so they don't reference the series or entries tables
+		`CREATE TABLE overrides (
+			sid        TEXT    NOT NULL,
+			eid        TEXT    NOT NULL,
+			title      TEXT,
+			author     TEXT,
+			summary    TEXT,
+			sort_title TEXT,
+			cover_page INTEGER,
+
+			PRIMARY KEY (sid, eid)
+		);`,
+	)},
+	{"add series covers", execStmts(
+		`ALTER TABLE series ADD COLUMN cover TEXT NOT NULL DEFAULT '';`,
+		`CREATE TABLE series_thumbnails (
+			sid       TEXT     PRIMARY KEY,
+			mod_time  DATETIME NOT NULL,
+			data      BLOB     NOT NULL,
+
+			-- Relationships
+			FOREIGN KEY (sid)
+				REFERENCES series (sid)
+                	ON UPDATE CASCADE 
+                	ON DELETE CASCADE
+			);`,
+	)},
+	{"add cover pages", execStmts(
+		`ALTER TABLE entries ADD COLUMN cover_page INTEGER NOT NULL DEFAULT 0;`,
+		// Thumbnails are outdated if they were made from a different page
+		`ALTER TABLE thumbnails ADD COLUMN page INTEGER NOT NULL DEFAULT 0;`,
+	)},
+	{"add tags", execStmts(
+		`CREATE TABLE tags (
+			tid  TEXT PRIMARY KEY,
+			name TEXT NOT NULL
+		);`,
+		`CREATE TABLE series_tags (
+			sid      TEXT    NOT NULL,
+			tid      TEXT    NOT NULL,
+			position INTEGER NOT NULL,
+
+			-- Relationships
+			PRIMARY KEY (sid, tid),
+			FOREIGN KEY (sid)
+				REFERENCES series (sid)
+                	ON UPDATE CASCADE
+                	ON DELETE CASCADE,
+			FOREIGN KEY (tid)
+				REFERENCES tags (tid)
+                	ON UPDATE CASCADE
+                	ON DELETE CASCADE
+			);`,
+		// Like overrides, edits must outlive the series
+		// they're made to, so they don't reference it
+		`CREATE TABLE tag_edits (
+			sid     TEXT    NOT NULL,
+			tid     TEXT    NOT NULL,
+			name    TEXT    NOT NULL,
+			added   INTEGER NOT NULL,
+
+			PRIMARY KEY (sid, tid)
+		);`,
+	)},
+	{"add creators", execStmts(
+		`CREATE TABLE creators (
+			cid  TEXT PRIMARY KEY,
+			name TEXT NOT NULL
+		);`,
+		`CREATE TABLE series_creators (
+			sid      TEXT    NOT NULL,
+			cid      TEXT    NOT NULL,
+			role     TEXT    NOT NULL,
+			position INTEGER NOT NULL,
+
+			-- Relationships
+			PRIMARY KEY (sid, cid, role),
+			FOREIGN KEY (sid)
+				REFERENCES series (sid)
+                	ON UPDATE CASCADE
+                	ON DELETE CASCADE,
+			FOREIGN KEY (cid)
+				REFERENCES creators (cid)
+                	ON UPDATE CASCADE
+                	ON DELETE CASCADE
+			);`,
+		`CREATE TABLE entry_creators (
+			sid      TEXT    NOT NULL,
+			eid      TEXT    NOT NULL,
+			cid      TEXT    NOT NULL,
+			role     TEXT    NOT NULL,
+			position INTEGER NOT NULL,
+
+			-- Relationships
+			PRIMARY KEY (sid, eid, cid, role),
+			FOREIGN KEY (sid, eid)
+				REFERENCES entries (sid, eid)
+                	ON UPDATE CASCADE
+                	ON DELETE CASCADE,
+			FOREIGN KEY (cid)
+				REFERENCES creators (cid)
+                	ON UPDATE CASCADE
+                	ON DELETE CASCADE
+			);`,
+	)},
+	{"add fingerprints", execStmts(
+		`ALTER TABLE entries ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '';`,
+	)},
+	{"add folders", execStmts(
+		`ALTER TABLE entries ADD COLUMN folder TEXT NOT NULL DEFAULT '';`,
+	)},
+	{"add duplicates", execStmts(
+		`ALTER TABLE series ADD COLUMN path TEXT NOT NULL DEFAULT '';`,
+		// Like overrides, resolutions don't reference the
+		// series or entries tables since duplicates aren't
+		// stored under the ID they share
+		`CREATE TABLE duplicates (
+			sid    TEXT NOT NULL,
+			eid    TEXT NOT NULL, -- Empty for duplicate series
+			prefer TEXT NOT NULL, -- Empty to keep every duplicate
+
+			PRIMARY KEY (sid, eid)
+		);`,
+	)},
+	{"add parse versions", execStmts(
+		// Entries parsed before versions were recorded are
+		// parsed again by the next scan
+		`ALTER TABLE entries ADD COLUMN parse_version INTEGER NOT NULL DEFAULT 0;`,
+	)},
+	{"add cover types", func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`ALTER TABLE entries ADD COLUMN cover_type TEXT NOT NULL DEFAULT '';`)
+		if err != nil {
+			return err
+		}
+		// Unchanged entries aren't rewritten by scans,
+		// so the stored entries are given their types
+		var es []Entry
+		if err := tx.Select(&es, `SELECT sid, eid, pages, cover_page FROM entries`); err != nil {
+			return err
+		}
+		for _, e := range es {
+			if err := setCoverType(tx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+}
+
+type migration struct {
+	name string
+	up   func(tx *sqlx.Tx) error
+}
+
+func execStmts(stmts ...string) func(tx *sqlx.Tx) error {
+	return func(tx *sqlx.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+type column struct {
+	table, name, def string
+}
+
+func addColumns(cols ...column) func(tx *sqlx.Tx) error {
+	return func(tx *sqlx.Tx) error {
+		for _, c := range cols {
+			var exists bool
+			err := tx.Get(&exists, `SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, c.table, c.name)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s;`, c.table, c.name, c.def))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+var errSchemaTooNew = errors.New("store schema is newer than supported")
+
+// The latest version of the schema this build supports
+var schemaVersion = len(migrations)
+
+func (s *Store) migrate() error {
+	_, err := s.pool.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER NOT NULL
+	);`)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > schemaVersion {
+		return fmt.Errorf("%w: %d > %d", errSchemaTooNew, current, schemaVersion)
+	}
+
+	for v := current + 1; v <= schemaVersion; v++ {
+		m := migrations[v-1]
+		err := s.tx(func(tx *sqlx.Tx) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`DELETE FROM schema_version`)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, v)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", v, m.name, err)
+		}
+		slog.Info("Migrated store", slog.Int("version", v), slog.String("name", m.name))
+	}
+
+	return nil
+}
+
+func (s *Store) SchemaVersion() (int, error) {
+	var v int
+	err := s.pool.Get(&v, `SELECT version FROM schema_version`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return v, err
+}
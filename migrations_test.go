@@ -0,0 +1,119 @@
+package tanuki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Migrate(t *testing.T) {
+	t.Run("new store", func(t *testing.T) {
+		s := mustOpenStoreMem(t)
+		defer mustCloseStore(t, s)
+
+		v, err := s.SchemaVersion()
+		require.NoError(t, err)
+		require.Equal(t, schemaVersion, v)
+	})
+
+	t.Run("reopened store", func(t *testing.T) {
+		s, tf := mustOpenStoreFile(t, nil)
+		defer tf.Close()
+		mustCloseStore(t, s)
+
+		s, _ = mustOpenStoreFile(t, tf)
+		defer mustCloseStore(t, s)
+		v, err := s.SchemaVersion()
+		require.NoError(t, err)
+		require.Equal(t, schemaVersion, v)
+	})
+
+	t.Run("unversioned store", func(t *testing.T) {
+		// Stores created before migrations existed only
+		// have the tables created by the first migration,
+		// tables are dropped before the ones they reference
+		s, tf := mustOpenStoreFile(t, nil)
+		defer tf.Close()
+		var tables []string
+		require.NoError(t, s.pool.Select(&tables, `SELECT name FROM sqlite_master 
+		                                           WHERE type = 'table' AND name != 'users'
+		                                           ORDER BY ROWID DESC`))
+		for _, table := range tables {
+			_, err := s.pool.Exec(`DROP TABLE ` + table)
+			require.NoError(t, err)
+		}
+		tx, err := s.pool.Beginx()
+		require.NoError(t, err)
+		require.NoError(t, migrations[0].up(tx))
+		_, err = tx.Exec(`INSERT INTO series (sid, title, mod_time, position, missing) VALUES ('a', 'b', ?, 1, 0)`, time.Now())
+		require.NoError(t, err)
+		_, err = tx.Exec(`INSERT INTO entries (eid, sid, title, archive, pages, mod_time, filesize, position, missing)
+		                  VALUES ('c', 'a', 'd', 'e', ?, ?, 1, 1, 0)`, Pages{{Path: "f.png", Mime: "image/png"}}, time.Now())
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		mustCloseStore(t, s)
+
+		// The data should be preserved after migrating
+		s, _ = mustOpenStoreFile(t, tf)
+		defer mustCloseStore(t, s)
+		v, err := s.SchemaVersion()
+		require.NoError(t, err)
+		require.Equal(t, schemaVersion, v)
+
+		e, err := s.GetEntry("a", "c")
+		require.NoError(t, err)
+		require.Equal(t, "d", e.Title)
+		require.Equal(t, "", e.Author)
+		require.Equal(t, ComicInfo{}, e.Info)
+		require.False(t, e.Volume.Valid)
+
+		// Stored entries are given their cover's type
+		types, err := s.GetSeriesCoverTypes()
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"a": "image/png"}, types)
+	})
+
+	t.Run("unversioned store with metadata columns", func(t *testing.T) {
+		// Versions before migrations existed added some of
+		// the metadata columns when they opened the store
+		s, tf := mustOpenStoreFile(t, nil)
+		defer tf.Close()
+		var tables []string
+		require.NoError(t, s.pool.Select(&tables, `SELECT name FROM sqlite_master 
+		                                           WHERE type = 'table' AND name != 'users'
+		                                           ORDER BY ROWID DESC`))
+		for _, table := range tables {
+			_, err := s.pool.Exec(`DROP TABLE ` + table)
+			require.NoError(t, err)
+		}
+		tx, err := s.pool.Beginx()
+		require.NoError(t, err)
+		require.NoError(t, migrations[0].up(tx))
+		_, err = tx.Exec(`ALTER TABLE entries ADD COLUMN author TEXT NOT NULL DEFAULT ''`)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		mustCloseStore(t, s)
+
+		s, _ = mustOpenStoreFile(t, tf)
+		defer mustCloseStore(t, s)
+		v, err := s.SchemaVersion()
+		require.NoError(t, err)
+		require.Equal(t, schemaVersion, v)
+
+		sr, es, err := ParseSeries("tests/lib/Akira")
+		require.NoError(t, err)
+		require.NoError(t, s.PopulateCatalog(map[Series][]Entry{sr: es}))
+	})
+
+	t.Run("newer store", func(t *testing.T) {
+		s, tf := mustOpenStoreFile(t, nil)
+		defer tf.Close()
+		_, err := s.pool.Exec(`UPDATE schema_version SET version = ?`, schemaVersion+1)
+		require.NoError(t, err)
+		mustCloseStore(t, s)
+
+		_, err = NewStore(tf.Name())
+		require.ErrorIs(t, err, errSchemaTooNew)
+	})
+}
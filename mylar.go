@@ -0,0 +1,61 @@
+package tanuki
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// Mylar
+
+const mylarFilename = "series.json"
+
+// Mylar stores a series.json sidecar in each series folder, it
+// describes the series using metadata taken from ComicVine
+type mylarSeries struct {
+	Metadata struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description_text"`
+		Publisher   string      `json:"publisher"`
+		Year        int         `json:"year"`
+		Status      string      `json:"status"`
+		ComicID     json.Number `json:"comicid"`
+	} `json:"metadata"`
+}
+
+func parseMylarSeries(path string) (mylarSeries, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return mylarSeries{}, err
+	}
+
+	var m mylarSeries
+	if err := json.Unmarshal(data, &m); err != nil {
+		return mylarSeries{}, err
+	}
+	return m, nil
+}
+
+// The sidecar describes the series as a whole, so
+// it takes precedence over metadata from the entries.
+// The folder's name is still the title, the sidecar's
+// name is kept alongside it
+func (m mylarSeries) apply(s *Series) {
+	md := m.Metadata
+	if name := strings.TrimSpace(md.Name); name != "" {
+		s.Info.Name = name
+	}
+	if md.Description != "" {
+		s.Info.Description = strings.TrimSpace(md.Description)
+	}
+	if md.Publisher != "" {
+		s.Info.Publisher = md.Publisher
+	}
+	if md.Year > 0 {
+		s.Info.Year = md.Year
+	}
+	if md.Status != "" {
+		s.Info.Status = md.Status
+	}
+	s.Info.ComicID = md.ComicID.String()
+}
@@ -0,0 +1,163 @@
+package tanuki
+
+import (
+	"cmp"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/maruel/natural"
+)
+
+// Span
+
+// Volumes and chapters are numbered, they can have
+// decimal numbers, e.g. "10.5", or cover a range of
+// numbers, e.g. "1-3"
+type Span struct {
+	Start float64
+	End   float64
+	Valid bool
+}
+
+func newSpan(start, end string) (Span, error) {
+	s, err := strconv.ParseFloat(start, 64)
+	if err != nil {
+		return Span{}, err
+	}
+	e := s
+	if end != "" {
+		e, err = strconv.ParseFloat(end, 64)
+		if err != nil {
+			return Span{}, err
+		}
+	}
+	if e < s {
+		return Span{}, fmt.Errorf("span ends before it starts: %s-%s", start, end)
+	}
+	return Span{Start: s, End: e, Valid: true}, nil
+}
+
+func parseSpan(s string) (Span, error) {
+	start, end, _ := strings.Cut(s, "-")
+	return newSpan(start, end)
+}
+
+func (s Span) String() string {
+	if !s.Valid {
+		return ""
+	}
+	start := strconv.FormatFloat(s.Start, 'f', -1, 64)
+	if s.End == s.Start {
+		return start
+	}
+	return start + "-" + strconv.FormatFloat(s.End, 'f', -1, 64)
+}
+
+func (s Span) compare(o Span) int {
+	if c := cmp.Compare(s.Start, o.Start); c != 0 {
+		return c
+	}
+	return cmp.Compare(s.End, o.End)
+}
+
+func (s Span) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return s.String(), nil
+}
+
+func (s *Span) Scan(src any) error {
+	var err error
+	switch src := src.(type) {
+	case nil:
+		*s = Span{}
+	case []byte:
+		*s, err = parseSpan(string(src))
+	case string:
+		*s, err = parseSpan(src)
+	default:
+		err = fmt.Errorf("incompatible type")
+	}
+	return err
+}
+
+// Parsing
+
+const numberPattern = `(\d+(?:\.\d+)?)`
+
+// A range either follows straight on from its start, e.g. "1-3", or
+// repeats the prefix, e.g. "v1 - v3", otherwise a number which only
+// follows the span, e.g. the year in "Vol 01 - 1984", would end it
+func spanPattern(prefix string) string {
+	return `(?i)(?:^|[^a-z])` + prefix + numberPattern +
+		`(?:-` + numberPattern + `|\s*-\s*` + prefix + numberPattern + `)?`
+}
+
+var (
+	volumeRegex = regexp.MustCompile(spanPattern(`(?:volume|vol|v)\.?\s*`))
+	// A bare "c" is only a prefix if the number follows it straight
+	// away and isn't followed by a letter, e.g. "c105" but not "c 2"
+	chapterRegex = regexp.MustCompile(spanPattern(`(?:(?:chapter|chap|ch)\.?\s*|c)`) + `\b`)
+)
+
+// Parses the volume and chapter numbers from
+// a filename, e.g. "Vol.01 Ch.0001-0002"
+func parseNumbers(name string) (volume, chapter Span) {
+	find := func(re *regexp.Regexp) Span {
+		m := re.FindStringSubmatch(name)
+		if m == nil {
+			return Span{}
+		}
+		s, err := newSpan(m[1], m[2]+m[3])
+		if err != nil {
+			return Span{}
+		}
+		return s
+	}
+	return find(volumeRegex), find(chapterRegex)
+}
+
+// Labels the entry with its numbers, e.g. "Vol 3 Ch 12"
+func (e Entry) numberLabel() string {
+	var parts []string
+	if e.Volume.Valid {
+		parts = append(parts, "Vol "+e.Volume.String())
+	}
+	if e.Chapter.Valid {
+		parts = append(parts, "Ch "+e.Chapter.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// Sorting
+
+// Entries are sorted by volume and then chapter, entries without
+// a volume come after those which have one, since they're usually
+// chapters which haven't been collected yet. Whole volumes come
+// before their chapters, and entries without any numbers, e.g.
+// extras, come last. Otherwise entries are sorted by their title
+func entryLess(a, b Entry) bool {
+	if a.Volume.Valid != b.Volume.Valid {
+		return a.Volume.Valid
+	}
+	if c := a.Volume.compare(b.Volume); a.Volume.Valid && c != 0 {
+		return c < 0
+	}
+
+	if a.Chapter.Valid != b.Chapter.Valid {
+		// Whole volumes come first but unnumbered entries come last
+		if a.Volume.Valid {
+			return !a.Chapter.Valid
+		}
+		return a.Chapter.Valid
+	}
+	if c := a.Chapter.compare(b.Chapter); a.Chapter.Valid && c != 0 {
+		return c < 0
+	}
+
+	return natural.Less(sortTitle(a.Title, a.SortTitle), sortTitle(b.Title, b.SortTitle))
+}
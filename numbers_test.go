@@ -0,0 +1,58 @@
+package tanuki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNumbers(t *testing.T) {
+	tests := []struct {
+		name    string
+		volume  string
+		chapter string
+	}{
+		{"Amano Megumi wa Suki Darake! v01", "1", ""},
+		{"Vol.01 Ch.0001 - A", "1", "1"},
+		{"Volume 10", "10", ""},
+		{"v2 ch. 3.5", "2", "3.5"},
+		{"Chapter 12-14", "", "12-14"},
+		{"Vol. 1-3", "1-3", ""},
+		{"Series c105.5", "", "105.5"},
+		{"20th Century Boys", "", ""},
+		{"Revolution 9", "", ""},
+		{"Extra", "", ""},
+		{"Vol 01 - 1984", "1", ""},
+		{"v1 - v3", "1-3", ""},
+		{"Ch. 5 - Ch. 7", "", "5-7"},
+		{"Chapter 12 - 14", "", "12"},
+		{"Tome c 2", "", ""},
+		{"Akira c2x", "", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			volume, chapter := parseNumbers(tc.name)
+			require.Equal(t, tc.volume, volume.String())
+			require.Equal(t, tc.chapter, chapter.String())
+		})
+	}
+}
+
+func TestSpan_Scan(t *testing.T) {
+	for _, s := range []Span{{}, {1, 1, true}, {2.5, 2.5, true}, {3, 5, true}} {
+		v, err := s.Value()
+		require.NoError(t, err)
+
+		var scanned Span
+		require.NoError(t, scanned.Scan(v))
+		require.Equal(t, s, scanned)
+	}
+}
+
+func TestEntry_NumberLabel(t *testing.T) {
+	e := Entry{Volume: Span{3, 3, true}, Chapter: Span{12, 12, true}}
+	require.Equal(t, "Vol 3 Ch 12", e.numberLabel())
+	require.Equal(t, "Ch 12", Entry{Chapter: e.Chapter}.numberLabel())
+	require.Equal(t, "", Entry{}.numberLabel())
+}
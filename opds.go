@@ -3,6 +3,7 @@ package tanuki
 import (
 	"encoding/xml"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -49,14 +50,92 @@ type opdsAuthor struct {
 	URI     string   `xml:"uri"`
 }
 
+// Contributor
+
+type opdsContributor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+// Category
+
+type opdsCategory struct {
+	Term   string `xml:"term,attr"`
+	Label  string `xml:"label,attr"`
+	Scheme string `xml:"scheme,attr,omitempty"`
+}
+
+// Genres don't have a scheme, the status of a series
+// and its reading direction are told apart from them
+// by their own
+const (
+	statusScheme    = "http://schema.org/creativeWorkStatus"
+	directionScheme = "http://www.idpf.org/2007/opf#page-progression-direction"
+)
+
+var rightToLeft = opdsCategory{Term: "rtl", Label: "Right to Left", Scheme: directionScheme}
+
 // Entry
 
+const dcNamespace = "http://purl.org/dc/terms/"
+
 type opdsEntry struct {
-	Title       string     `xml:"title"`
-	LastUpdated opdsTime   `xml:"updated"`
-	ID          string     `xml:"id"`
-	Content     string     `xml:"content"` // Empty but tag should still exist
-	Link        []opdsLink `xml:"link"`
+	Title        string            `xml:"title"`
+	Authors      []opdsAuthor      `xml:"author"`
+	Contributors []opdsContributor `xml:"contributor"`
+	LastUpdated  opdsTime          `xml:"updated"`
+	ID           string            `xml:"id"`
+	Summary      string            `xml:"summary,omitempty"`
+	Categories   []opdsCategory    `xml:"category"`
+	Content      string            `xml:"content"` // Empty but tag should still exist
+	Link         []opdsLink        `xml:"link"`
+
+	// Dublin Core metadata
+	Namespace string `xml:"xmlns:dc,attr,omitempty"`
+	Publisher string `xml:"dc:publisher,omitempty"`
+	Issued    string `xml:"dc:issued,omitempty"`
+	Language  string `xml:"dc:language,omitempty"`
+	Audience  string `xml:"dc:audience,omitempty"`
+	IsPartOf  string `xml:"dc:isPartOf,omitempty"`
+	// Alternative title
+	Alternative string `xml:"dc:alternative,omitempty"`
+}
+
+func (e *opdsEntry) setMetadata(publisher string, year int, language, ageRating string, genres []string) {
+	e.Publisher = publisher
+	if year > 0 {
+		e.Issued = fmt.Sprintf("%04d", year)
+	}
+	e.Language = language
+	e.Audience = ageRating
+	if e.Publisher != "" || e.Issued != "" || e.Language != "" || e.Audience != "" || e.IsPartOf != "" ||
+		e.Alternative != "" {
+		e.Namespace = dcNamespace
+	}
+
+	for _, g := range genres {
+		e.Categories = append(e.Categories, opdsCategory{Term: g, Label: g})
+	}
+}
+
+// Writers and artists are authors, everyone else is a
+// contributor. The creators link to their own feeds
+func (e *opdsEntry) setCredits(credits []Credit) {
+	seen := make(map[string]struct{})
+	for _, c := range credits {
+		cid := creatorID(c.Name)
+		if _, found := seen[cid]; found {
+			continue
+		}
+		seen[cid] = struct{}{}
+
+		uri := opdsRoot + "/creators/" + cid
+		if c.Role == RoleWriter || c.Role == RoleArtist {
+			e.Authors = append(e.Authors, opdsAuthor{Name: c.Name, URI: uri})
+		} else {
+			e.Contributors = append(e.Contributors, opdsContributor{Name: c.Name, URI: uri})
+		}
+	}
 }
 
 // Links
@@ -122,35 +201,66 @@ func (f *opdsFeed) addLink(href string, r opdsRelation, t opdsType) {
 	})
 }
 
-func (f *opdsFeed) addSeries(s *Series) {
-	f.Entries = append(f.Entries, opdsEntry{
+// The cover's type is its first entry's cover's
+// type if the series doesn't have its own cover
+func (f *opdsFeed) addSeries(s *Series, coverType string) {
+	seriesPath := opdsRoot + "/series/" + s.SID
+
+	entry := opdsEntry{
 		Title:       s.Title,
 		LastUpdated: opdsTime{s.ModTime},
 		ID:          s.SID,
-		Content:     "",
+		Summary:     s.Info.Description,
 		Link: []opdsLink{
-			simpleLink{Href: opdsRoot + "/series/" + s.SID, Rel: relSubsection, Type: typeAcquisition},
+			simpleLink{Href: seriesPath + "/cover?thumbnail=true", Rel: relThumbnail, Type: "image/jpeg"},
+			simpleLink{Href: seriesPath + "/cover", Rel: relCover, Type: opdsType(coverType)},
+			simpleLink{Href: seriesPath, Rel: relSubsection, Type: typeAcquisition},
 		},
-	})
+	}
+	if s.Info.Name != s.Title {
+		entry.Alternative = s.Info.Name
+	}
+	entry.setMetadata(s.Info.Publisher, s.Info.Year, s.Info.LanguageISO, s.Info.AgeRating, s.Info.Genres())
+	if s.Info.Status != "" {
+		entry.Categories = append(entry.Categories,
+			opdsCategory{Term: s.Info.Status, Label: s.Info.Status, Scheme: statusScheme})
+	}
+	if s.Info.RightToLeft() {
+		entry.Categories = append(entry.Categories, rightToLeft)
+	}
+	entry.setCredits(s.Credits())
+	f.Entries = append(f.Entries, entry)
 }
 
 func (f *opdsFeed) addEntry(e *Entry) {
-	content := fmt.Sprintf("zip - %.1f MiB", float64(e.Filesize)/1024/1024)
+	// Only folders of images don't have an archive extension
+	archive, found := archiveTypeOf(e.Archive)
+	if !found {
+		archive = dirType
+	}
+	content := fmt.Sprintf("%s - %.1f MiB", archive.Format, float64(e.Filesize)/1024/1024)
 	if float64(e.Filesize)/1024 < 500 { // Under 500 KiB
-		content = fmt.Sprintf("zip - %.1f KiB", float64(e.Filesize)/1024)
+		content = fmt.Sprintf("%s - %.1f KiB", archive.Format, float64(e.Filesize)/1024)
+	}
+	if label := e.numberLabel(); label != "" {
+		content = label + " - " + content
 	}
-	entryPath := fmt.Sprintf("%s/series/%s/entries/%s", opdsRoot, f.ID, e.EID)
-	coverType := opdsType(e.Pages[0].Mime)
+	if heading := e.Info.heading(); heading != "" {
+		content = heading + " - " + content
+	}
+	entryPath := fmt.Sprintf("%s/series/%s/entries/%s", opdsRoot, e.SID, e.EID)
+	coverType := opdsType(e.Pages[e.CoverPage].Mime)
 
-	f.Entries = append(f.Entries, opdsEntry{
+	entry := opdsEntry{
 		Title:       e.Title,
 		LastUpdated: opdsTime{e.ModTime},
 		ID:          e.EID,
+		Summary:     e.Info.Summary,
 		Content:     content,
 		Link: []opdsLink{
 			simpleLink{Href: entryPath + "/cover?thumbnail=true", Rel: relThumbnail, Type: "image/jpeg"},
 			simpleLink{Href: entryPath + "/cover", Rel: relCover, Type: coverType},
-			simpleLink{Href: entryPath + "/archive", Rel: relAcquisition, Type: "application/zip"},
+			simpleLink{Href: entryPath + "/archive", Rel: relAcquisition, Type: opdsType(archive.Mime)},
 			streamingLink{
 				simpleLink: simpleLink{
 					Href: entryPath + "/page/{pageNumber}",
@@ -161,6 +271,69 @@ func (f *opdsFeed) addEntry(e *Entry) {
 				PageCount: len(e.Pages),
 			},
 		},
+	}
+	// The series named by the metadata may differ from the one
+	// the entry's in, e.g. if it's been put in a collection
+	entry.IsPartOf = e.Info.Series
+	entry.setMetadata(e.Info.Publisher, e.Info.Year, e.Info.LanguageISO, e.Info.AgeRating, e.Info.Genres())
+	if e.Info.RightToLeft() {
+		entry.Categories = append(entry.Categories, rightToLeft)
+	}
+	entry.setCredits(e.Credits())
+	f.Entries = append(f.Entries, entry)
+}
+
+func (f *opdsFeed) addFolder(fd *Folder) {
+	f.Entries = append(f.Entries, opdsEntry{
+		Title:       fd.Title,
+		LastUpdated: opdsTime{fd.ModTime},
+		ID:          fd.FID,
+		Content:     fmt.Sprintf("%d entries", fd.Count),
+		Link: []opdsLink{
+			simpleLink{Href: fmt.Sprintf("%s/series/%s/folders/%s", opdsRoot, fd.SID, fd.FID),
+				Rel: relSubsection, Type: typeAcquisition},
+		},
+	})
+}
+
+// Links to every entry in the series, regardless of their folder
+func (f *opdsFeed) addAllEntries(s *Series, count int) {
+	f.Entries = append(f.Entries, opdsEntry{
+		Title:       "All Entries",
+		LastUpdated: opdsTime{s.ModTime},
+		ID:          s.SID + "-all",
+		Content:     fmt.Sprintf("%d entries", count),
+		Link: []opdsLink{
+			simpleLink{Href: opdsRoot + "/series/" + s.SID + "/all", Rel: relSubsection, Type: typeAcquisition},
+		},
+	})
+}
+
+func (f *opdsFeed) addTag(t *Tag) {
+	f.Entries = append(f.Entries, opdsEntry{
+		Title:       t.Name,
+		LastUpdated: opdsTime{t.ModTime},
+		ID:          t.TID,
+		Content:     fmt.Sprintf("%d series", t.Count),
+		Link: []opdsLink{
+			simpleLink{Href: opdsRoot + "/tags/" + t.TID, Rel: relSubsection, Type: typeNavigation},
+		},
+	})
+}
+
+func (f *opdsFeed) addCreator(c *Creator) {
+	roles := make([]string, len(c.Roles))
+	for i, r := range c.Roles {
+		roles[i] = string(r)
+	}
+	f.Entries = append(f.Entries, opdsEntry{
+		Title:       c.Name,
+		LastUpdated: opdsTime{c.ModTime},
+		ID:          c.CID,
+		Content:     fmt.Sprintf("%s - %d series", strings.Join(roles, ", "), c.Count),
+		Link: []opdsLink{
+			simpleLink{Href: opdsRoot + "/creators/" + c.CID, Rel: relSubsection, Type: typeNavigation},
+		},
 	})
 }
 
@@ -54,6 +54,173 @@ func TestOPDS_Search(t *testing.T) {
 	})
 }
 
+func TestOPDS_Entry(t *testing.T) {
+	tests := []struct {
+		archive  string
+		content  string
+		mimeType opdsType
+	}{
+		{"a/b.zip", "zip - 1.0 KiB", "application/zip"},
+		{"a/b.cbz", "zip - 1.0 KiB", "application/zip"},
+		{"a/b.rar", "rar - 1.0 KiB", "application/vnd.rar"},
+		{"a/b.CBR", "rar - 1.0 KiB", "application/vnd.comicbook-rar"},
+		{"a/b.7z", "7z - 1.0 KiB", "application/x-7z-compressed"},
+		{"a/b.cb7", "7z - 1.0 KiB", "application/x-cb7"},
+		{"a/b.cbt", "tar - 1.0 KiB", "application/x-cbt"},
+		{"a/b.v1.tar.gz", "tar.gz - 1.0 KiB", "application/gzip"},
+		{"a/b.pdf", "pdf - 1.0 KiB", "application/pdf"},
+		{"a/b.epub", "epub - 1.0 KiB", "application/epub+zip"},
+		{"a/Ch. 1.5", "folder - 1.0 KiB", "application/zip"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.archive, func(t *testing.T) {
+			f := newOpdsFeed("a", "b", time.Time{}, opdsAuthor{})
+			f.addEntry(&Entry{
+				EID:      "c",
+				SID:      "a",
+				Archive:  tc.archive,
+				Filesize: 1024,
+				Pages:    Pages{{Path: "d.jpg", Mime: "image/jpeg"}},
+			})
+
+			require.Len(t, f.Entries, 1)
+			require.Equal(t, tc.content, f.Entries[0].Content)
+			require.Contains(t, f.Entries[0].Link, opdsLink(simpleLink{
+				Href: "/opds/v1.2/series/a/entries/c/archive",
+				Rel:  relAcquisition,
+				Type: tc.mimeType,
+			}))
+		})
+	}
+}
+
+func TestOPDS_EntryAuthor(t *testing.T) {
+	f := newOpdsFeed("a", "b", time.Time{}, opdsAuthor{})
+	f.addEntry(&Entry{EID: "c", Archive: "a/b.zip", Pages: Pages{{Path: "d.jpg", Mime: "image/jpeg"}}})
+	f.addEntry(&Entry{EID: "d", Archive: "a/b.epub", Author: "e", Pages: Pages{{Path: "d.jpg", Mime: "image/jpeg"}}})
+
+	require.Nil(t, f.Entries[0].Authors)
+	require.Equal(t, []opdsAuthor{{Name: "e", URI: "/opds/v1.2/creators/" + creatorID("e")}}, f.Entries[1].Authors)
+
+	t.Run("multiple creators", func(t *testing.T) {
+		f.addEntry(&Entry{
+			EID:     "e",
+			Archive: "a/b.zip",
+			Author:  "f, g",
+			Pages:   Pages{{Path: "d.jpg", Mime: "image/jpeg"}},
+			Info:    ComicInfo{Penciller: "g, h", Translator: "i", Letterer: "j"},
+		})
+		require.Equal(t, []opdsAuthor{
+			{Name: "f", URI: "/opds/v1.2/creators/" + creatorID("f")},
+			{Name: "g", URI: "/opds/v1.2/creators/" + creatorID("g")},
+			{Name: "h", URI: "/opds/v1.2/creators/" + creatorID("h")},
+		}, f.Entries[2].Authors)
+		require.Equal(t, []opdsContributor{
+			{Name: "i", URI: "/opds/v1.2/creators/" + creatorID("i")},
+			{Name: "j", URI: "/opds/v1.2/creators/" + creatorID("j")},
+		}, f.Entries[2].Contributors)
+	})
+}
+
+func TestOPDS_EntryMetadata(t *testing.T) {
+	f := newOpdsFeed("a", "b", time.Time{}, opdsAuthor{})
+	f.addEntry(&Entry{
+		EID:     "c",
+		Title:   "d",
+		Author:  "e",
+		Archive: "a/b.zip",
+		Pages:   Pages{{Path: "f.jpg", Mime: "image/jpeg"}},
+		Info: ComicInfo{
+			Summary:     "g",
+			Writer:      "e",
+			Penciller:   "h",
+			Publisher:   "i",
+			Year:        2001,
+			LanguageISO: "en",
+			AgeRating:   "Teen",
+			Genre:       "j, k",
+			Series:      "l",
+			Number:      "2",
+			Title:       "m",
+			Manga:       "YesAndRightToLeft",
+		},
+	})
+	f.Entries[0].Link = nil
+
+	expected := `
+<opdsEntry xmlns:dc="http://purl.org/dc/terms/">
+  <title>d</title>
+  <author>
+    <name>e</name>
+    <uri>/opds/v1.2/creators/P3m7e0NbBTIWUdrv03TNxoHcBvqmXjdOODN7iMoEbeo</uri>
+  </author>
+  <author>
+    <name>h</name>
+    <uri>/opds/v1.2/creators/qqlAJmTxpB9A67xSyZk-tmrrNmYClY_fqig7ceZNsSM</uri>
+  </author>
+  <updated>0001-01-01T00:00:00Z</updated>
+  <id>c</id>
+  <summary>g</summary>
+  <category term="j" label="j"></category>
+  <category term="k" label="k"></category>
+  <category term="rtl" label="Right to Left" scheme="http://www.idpf.org/2007/opf#page-progression-direction"></category>
+  <content>l #2: m - zip - 0.0 KiB</content>
+  <dc:publisher>i</dc:publisher>
+  <dc:issued>2001</dc:issued>
+  <dc:language>en</dc:language>
+  <dc:audience>Teen</dc:audience>
+  <dc:isPartOf>l</dc:isPartOf>
+</opdsEntry>`
+
+	b, err := xml.MarshalIndent(f.Entries[0], "", "  ")
+	require.NoError(t, err)
+	require.Equal(t, trimNewline(expected), string(b))
+
+	t.Run("series", func(t *testing.T) {
+		f := newOpdsFeed("a", "b", time.Time{}, opdsAuthor{})
+		f.addSeries(&Series{SID: "c", Title: "d"}, "")
+		require.Empty(t, f.Entries[0].Namespace)
+
+		f.addSeries(&Series{SID: "c", Title: "d", Info: SeriesInfo{Year: 1999, Genre: "e"}}, "")
+		require.Equal(t, dcNamespace, f.Entries[1].Namespace)
+		require.Equal(t, "1999", f.Entries[1].Issued)
+		require.Equal(t, []opdsCategory{{Term: "e", Label: "e"}}, f.Entries[1].Categories)
+
+		f.addSeries(&Series{SID: "c", Title: "d", Info: SeriesInfo{Description: "f", Status: "Ended"}}, "")
+		require.Equal(t, "f", f.Entries[2].Summary)
+		require.Empty(t, f.Entries[2].Content)
+		require.Equal(t, []opdsCategory{{Term: "Ended", Label: "Ended", Scheme: statusScheme}}, f.Entries[2].Categories)
+
+		f.addSeries(&Series{SID: "c", Title: "d", Info: SeriesInfo{Manga: "YesAndRightToLeft"}}, "")
+		require.Equal(t, []opdsCategory{rightToLeft}, f.Entries[3].Categories)
+
+		// Names which are the title aren't repeated
+		f.addSeries(&Series{SID: "c", Title: "d", Info: SeriesInfo{Name: "d"}}, "")
+		require.Empty(t, f.Entries[4].Alternative)
+		require.Empty(t, f.Entries[4].Namespace)
+		f.addSeries(&Series{SID: "c", Title: "d", Info: SeriesInfo{Name: "g"}}, "")
+		require.Equal(t, "g", f.Entries[5].Alternative)
+		require.Equal(t, dcNamespace, f.Entries[5].Namespace)
+	})
+}
+
+func TestOPDS_Overrides(t *testing.T) {
+	f := newOpdsFeed("a", "b", time.Time{}, opdsAuthor{})
+	f.addEntry(&Entry{
+		EID:       "c",
+		SID:       "a",
+		Archive:   "a/b.zip",
+		Pages:     Pages{{Path: "d.jpg", Mime: "image/jpeg"}, {Path: "e.png", Mime: "image/png"}},
+		CoverPage: 1,
+	})
+	require.Contains(t, f.Entries[0].Link, opdsLink(simpleLink{
+		Href: "/opds/v1.2/series/a/entries/c/cover",
+		Rel:  relCover,
+		Type: "image/png",
+	}))
+}
+
 // Utils
 
 func trimNewline(l string) string {
@@ -0,0 +1,212 @@
+package tanuki
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Overrides
+
+// Admins can override the metadata of series and entries, overrides
+// are stored separately so that they persist across rescans. Series
+// overrides have an empty EID
+type override struct {
+	SID       string
+	EID       string
+	Title     *string
+	Author    *string
+	Summary   *string
+	SortTitle *string
+	CoverPage *int
+}
+
+// Maps the fields which can be overridden to their column
+var overrideFields = map[string]string{
+	"title":      "title",
+	"author":     "author",
+	"summary":    "summary",
+	"sort-title": "sort_title",
+	"cover":      "cover_page",
+}
+
+var (
+	errInvalidOverrideField = errors.New("invalid override field")
+	errInvalidCoverPage     = errors.New("invalid cover page")
+)
+
+func (o override) applySeries(s *Series) {
+	if o.Title != nil {
+		s.Title = *o.Title
+	}
+	if o.Author != nil {
+		s.Author = *o.Author
+	}
+	if o.Summary != nil {
+		s.Info.Description = *o.Summary
+	}
+	if o.SortTitle != nil {
+		s.SortTitle = *o.SortTitle
+	}
+}
+
+func (o override) applyEntry(e *Entry) {
+	if o.Title != nil {
+		e.Title = *o.Title
+	}
+	if o.Author != nil {
+		e.Author = *o.Author
+	}
+	if o.Summary != nil {
+		e.Info.Summary = *o.Summary
+	}
+	if o.SortTitle != nil {
+		e.SortTitle = *o.SortTitle
+	}
+	// The entry could have lost pages since the override was set
+	if o.CoverPage != nil && *o.CoverPage < len(e.Pages) {
+		e.CoverPage = *o.CoverPage
+	}
+}
+
+// Returns the overrides of the series and all its
+// entries, the series' override is keyed by ""
+func (s *Store) getOverrides(q sqlx.Queryer, sid string) (map[string]override, error) {
+	var ovs []override
+	err := sqlx.Select(q, &ovs, `SELECT sid, eid, title, author, summary, sort_title, cover_page 
+		                        FROM overrides WHERE sid = ?`, sid)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]override, len(ovs))
+	for _, o := range ovs {
+		m[o.EID] = o
+	}
+	return m, nil
+}
+
+func (s *Store) getAllSeriesOverrides(q sqlx.Queryer) (map[string]override, error) {
+	var ovs []override
+	err := sqlx.Select(q, &ovs, `SELECT sid, eid, title, author, summary, sort_title, cover_page 
+		                        FROM overrides WHERE eid = ''`)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]override, len(ovs))
+	for _, o := range ovs {
+		m[o.SID] = o
+	}
+	return m, nil
+}
+
+// A nil value removes the override, an empty value
+// overrides the field with an empty value
+func (s *Store) setOverride(tx *sqlx.Tx, sid, eid, field string, value *string) error {
+	col, found := overrideFields[field]
+	if !found {
+		return fmt.Errorf("%w: %s", errInvalidOverrideField, field)
+	}
+
+	stmt := fmt.Sprintf(`INSERT INTO overrides (sid, eid, %[1]s) VALUES (?, ?, ?)
+		                 ON CONFLICT (sid, eid) DO UPDATE SET %[1]s=excluded.%[1]s`, col)
+	_, err := tx.Exec(stmt, sid, eid, value)
+	return err
+}
+
+func (s *Store) SetSeriesOverride(sid, field, value string) error {
+	return s.editSeriesOverride(sid, field, &value)
+}
+
+func (s *Store) RemoveSeriesOverride(sid, field string) error {
+	return s.editSeriesOverride(sid, field, nil)
+}
+
+func (s *Store) editSeriesOverride(sid, field string, value *string) error {
+	return s.tx(func(tx *sqlx.Tx) error {
+		var exists bool
+		if err := tx.Get(&exists, `SELECT COUNT(*) > 0 FROM series WHERE sid = ?`, sid); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("series does not exist: %s", sid)
+		}
+		if field == "cover" {
+			return fmt.Errorf("%w: series do not have pages", errInvalidOverrideField)
+		}
+		if err := s.setOverride(tx, sid, "", field, value); err != nil {
+			return err
+		}
+
+		// The author is credited as the series' writer
+		if field == "author" {
+			var sr Series
+			if err := tx.Get(&sr, `SELECT sid, author, info FROM series WHERE sid = ?`, sid); err != nil {
+				return err
+			}
+			if err := s.setSeriesCredits(tx, sr); err != nil {
+				return err
+			}
+			return s.deleteUnusedCreators(tx)
+		}
+		return nil
+	})
+}
+
+func (s *Store) SetEntryOverride(sid, eid, field, value string) error {
+	return s.editEntryOverride(sid, eid, field, &value)
+}
+
+func (s *Store) RemoveEntryOverride(sid, eid, field string) error {
+	return s.editEntryOverride(sid, eid, field, nil)
+}
+
+func (s *Store) editEntryOverride(sid, eid, field string, value *string) error {
+	return s.tx(func(tx *sqlx.Tx) error {
+		e, err := s.getEntry(tx, sid, eid)
+		if err != nil {
+			return err
+		}
+
+		if field == "cover" && value != nil {
+			// Covers are zero-indexed, like pages
+			n, err := strconv.Atoi(*value)
+			if err != nil || n < 0 || n >= len(e.Pages) {
+				return fmt.Errorf("%w: %s", errInvalidCoverPage, *value)
+			}
+			v := strconv.Itoa(n)
+			value = &v
+		}
+		// Thumbnails know which page they were made
+		// from, so they're regenerated if it changes
+		if err := s.setOverride(tx, sid, eid, field, value); err != nil {
+			return err
+		}
+
+		if field == "cover" {
+			var raw Entry
+			err := tx.Get(&raw, `SELECT sid, eid, pages, cover_page FROM entries WHERE sid = ? AND eid = ?`, sid, eid)
+			if err != nil {
+				return err
+			}
+			return setCoverType(tx, raw)
+		}
+
+		// The author is credited as the entry's writer
+		if field == "author" {
+			var raw Entry
+			err := tx.Get(&raw, `SELECT sid, eid, author, info FROM entries WHERE sid = ? AND eid = ?`, sid, eid)
+			if err != nil {
+				return err
+			}
+			if err := s.setEntryCredits(tx, raw); err != nil {
+				return err
+			}
+			return s.deleteUnusedCreators(tx)
+		}
+		return nil
+	})
+}
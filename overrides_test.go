@@ -0,0 +1,142 @@
+package tanuki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_SetSeriesOverride(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	lib, err := ParseLibrary("tests/lib", LibraryOptions{})
+	require.NoError(t, err)
+	require.NoError(t, s.PopulateCatalog(lib))
+
+	t.Run("overridden values", func(t *testing.T) {
+		require.NoError(t, s.SetSeriesOverride(akiraSeries.SID, "title", "AKIRA"))
+		require.NoError(t, s.SetSeriesOverride(akiraSeries.SID, "author", "Katsuhiro Otomo"))
+		require.NoError(t, s.SetSeriesOverride(akiraSeries.SID, "summary", "Neo-Tokyo"))
+
+		sr, err := s.GetSeries(akiraSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, "AKIRA", sr.Title)
+		require.Equal(t, "Katsuhiro Otomo", sr.Author)
+		require.Equal(t, "Neo-Tokyo", sr.Info.Description)
+	})
+
+	t.Run("sort title", func(t *testing.T) {
+		require.NoError(t, s.SetSeriesOverride(centurySeries.SID, "sort-title", "Twentieth Century Boys"))
+
+		ctl, err := s.GetCatalog()
+		require.NoError(t, err)
+		require.Len(t, ctl, 3)
+		require.Equal(t, akiraSeries.SID, ctl[0].SID)
+		require.Equal(t, amanoSeries.SID, ctl[1].SID)
+		require.Equal(t, centurySeries.SID, ctl[2].SID)
+		require.Equal(t, "Twentieth Century Boys", ctl[2].SortTitle)
+	})
+
+	t.Run("preserved across rescans", func(t *testing.T) {
+		require.NoError(t, s.PopulateCatalog(lib))
+		sr, err := s.GetSeries(akiraSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, "AKIRA", sr.Title)
+
+		// Even if the series is missing for a scan
+		partial := map[Series][]Entry{centurySeries: centuryEntries}
+		require.NoError(t, s.PopulateCatalog(partial))
+		require.NoError(t, s.PopulateCatalog(lib))
+		sr, err = s.GetSeries(akiraSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, "AKIRA", sr.Title)
+	})
+
+	t.Run("removed override", func(t *testing.T) {
+		require.NoError(t, s.RemoveSeriesOverride(akiraSeries.SID, "title"))
+		sr, err := s.GetSeries(akiraSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, akiraSeries.Title, sr.Title)
+		require.Equal(t, "Katsuhiro Otomo", sr.Author)
+	})
+
+	t.Run("empty override", func(t *testing.T) {
+		require.NoError(t, s.SetSeriesOverride(akiraSeries.SID, "author", ""))
+		sr, err := s.GetSeries(akiraSeries.SID)
+		require.NoError(t, err)
+		require.Empty(t, sr.Author)
+
+		require.NoError(t, s.RemoveSeriesOverride(akiraSeries.SID, "author"))
+		sr, err = s.GetSeries(akiraSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, akiraSeries.Author, sr.Author)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.ErrorIs(t, s.SetSeriesOverride(akiraSeries.SID, "a", "b"), errInvalidOverrideField)
+		require.ErrorIs(t, s.RemoveSeriesOverride(akiraSeries.SID, "a"), errInvalidOverrideField)
+		require.ErrorIs(t, s.SetSeriesOverride(akiraSeries.SID, "cover", "1"), errInvalidOverrideField)
+		require.Error(t, s.SetSeriesOverride("a", "title", "b"))
+	})
+}
+
+func TestStore_SetEntryOverride(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	lib, err := ParseLibrary("tests/lib", LibraryOptions{})
+	require.NoError(t, err)
+	require.NoError(t, s.PopulateCatalog(lib))
+
+	sid, eid := akiraSeries.SID, akiraEntries[0].EID
+
+	t.Run("overridden values", func(t *testing.T) {
+		require.NoError(t, s.SetEntryOverride(sid, eid, "title", "Vol. 1"))
+		require.NoError(t, s.SetEntryOverride(sid, eid, "summary", "a"))
+
+		e, err := s.GetEntry(sid, eid)
+		require.NoError(t, err)
+		require.Equal(t, "Vol. 1", e.Title)
+		require.Equal(t, "a", e.Info.Summary)
+
+		es, err := s.GetEntries(sid)
+		require.NoError(t, err)
+		require.Equal(t, "Vol. 1", es[0].Title)
+		require.Equal(t, akiraEntries[1].Title, es[1].Title)
+	})
+
+	t.Run("cover", func(t *testing.T) {
+		original, _, err := s.GetThumbnail(sid, eid)
+		require.NoError(t, err)
+
+		require.NoError(t, s.SetEntryOverride(sid, eid, "cover", "5"))
+		e, err := s.GetEntry(sid, eid)
+		require.NoError(t, err)
+		require.Equal(t, 5, e.CoverPage)
+
+		expected, _, err := s.GetPage(sid, eid, 5)
+		require.NoError(t, err)
+		cover, _, err := s.GetCover(sid, eid)
+		require.NoError(t, err)
+		require.Equal(t, expected, cover)
+
+		// The thumbnail should be regenerated from the new cover
+		thumb, _, err := s.GetThumbnail(sid, eid)
+		require.NoError(t, err)
+		require.NotEqual(t, original, thumb)
+
+		require.NoError(t, s.RemoveEntryOverride(sid, eid, "cover"))
+		thumb, _, err = s.GetThumbnail(sid, eid)
+		require.NoError(t, err)
+		require.Equal(t, original, thumb)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.ErrorIs(t, s.SetEntryOverride(sid, eid, "cover", ""), errInvalidCoverPage)
+		require.ErrorIs(t, s.SetEntryOverride(sid, eid, "cover", "-1"), errInvalidCoverPage)
+		require.ErrorIs(t, s.SetEntryOverride(sid, eid, "cover", "100"), errInvalidCoverPage)
+		require.ErrorIs(t, s.SetEntryOverride(sid, eid, "a", "b"), errInvalidOverrideField)
+		require.Error(t, s.SetEntryOverride(sid, "a", "title", "b"))
+	})
+}
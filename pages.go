@@ -0,0 +1,90 @@
+package tanuki
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"log/slog"
+)
+
+// Page Metadata
+
+// Image headers are at the start of the image, though JPEGs
+// can have large metadata segments before their dimensions
+const pageHeaderSize = 256 << 10
+
+// Measures the dimensions of each page, only as much of the
+// page as its header needs is read if the format can stream
+// it. Sizes come from the archive's listing, so pages are
+// only read in full if it doesn't list them or the header
+// isn't at the start of the page. Pages which can't be
+// measured are left as they are, since readers can still
+// read them
+func measurePages(a archive, pages Pages) {
+	// Sequential formats are read once rather than once per page
+	if w, ok := a.(fileWalker); ok {
+		index := make(map[string]int, len(pages))
+		for i, p := range pages {
+			index[p.Path] = i
+		}
+		err := w.walkFiles(func(name string, r io.Reader) error {
+			if i, found := index[name]; found {
+				measurePage(&pages[i], r)
+			}
+			return nil
+		})
+		if err != nil {
+			slog.Debug("Could not read pages", slog.Any("err", err))
+		}
+		return
+	}
+
+	for i, p := range pages {
+		r, err := openPage(a, p)
+		if err != nil {
+			slog.Debug("Could not read page", slog.String("page", p.Path), slog.Any("err", err))
+			continue
+		}
+		measurePage(&pages[i], r)
+		r.Close()
+	}
+}
+
+// Streams the page if the format can, otherwise it's read in full
+func openPage(a archive, p Page) (io.ReadCloser, error) {
+	if o, ok := a.(pageOpener); ok {
+		if r, err := o.open(p); err == nil {
+			return r, nil
+		}
+	}
+	data, err := a.read(p)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Only the page's header is read, unless its size
+// isn't known or its header can't be decoded
+func measurePage(p *Page, r io.Reader) {
+	var header bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(io.LimitReader(r, pageHeaderSize), &header))
+	if err != nil || p.Bytes == 0 {
+		data, rErr := io.ReadAll(io.MultiReader(&header, r))
+		if rErr != nil {
+			slog.Debug("Could not read page", slog.String("page", p.Path), slog.Any("err", rErr))
+			return
+		}
+		p.Bytes = int64(len(data))
+		if err != nil {
+			cfg, _, err = image.DecodeConfig(bytes.NewReader(data))
+		}
+	}
+	if err != nil {
+		slog.Debug("Could not decode page", slog.String("page", p.Path), slog.Any("err", err))
+		return
+	}
+	p.Width = cfg.Width
+	p.Height = cfg.Height
+	p.Wide = cfg.Width > cfg.Height
+}
@@ -0,0 +1,104 @@
+package tanuki
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasurePages(t *testing.T) {
+	a := memArchive{
+		"tall.png": mustEncodePNG(t, image.NewGray(image.Rect(0, 0, 10, 20))),
+		"wide.png": mustEncodePNG(t, image.NewGray(image.Rect(0, 0, 40, 20))),
+		"bad.png":  []byte("not an image"),
+	}
+	pages := Pages{{Path: "tall.png"}, {Path: "wide.png"}, {Path: "bad.png"}}
+	measurePages(a, pages)
+
+	require.Equal(t, Page{Path: "tall.png", Width: 10, Height: 20, Bytes: int64(len(a["tall.png"]))}, pages[0])
+	require.Equal(t, Page{Path: "wide.png", Width: 40, Height: 20, Bytes: int64(len(a["wide.png"])), Wide: true}, pages[1])
+
+	// Pages which can't be decoded still have their size
+	require.Equal(t, Page{Path: "bad.png", Bytes: 12}, pages[2])
+}
+
+// Pages can only be streamed, and the bytes read from them are counted
+type streamedArchive struct {
+	memArchive
+	n *int64
+}
+
+func (a streamedArchive) read(p Page) ([]byte, error) {
+	return nil, errors.New("page read in full")
+}
+
+func (a streamedArchive) open(p Page) (io.ReadCloser, error) {
+	return io.NopCloser(&countingReader{r: bytes.NewReader(a.memArchive[p.Path]), n: a.n}), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	*r.n += int64(n)
+	return n, err
+}
+
+func TestMeasurePages_Streamed(t *testing.T) {
+	// The page is padded well past its header
+	data := mustEncodePNG(t, image.NewGray(image.Rect(0, 0, 40, 20)))
+	data = append(data, make([]byte, 4*pageHeaderSize)...)
+	var n int64
+	a := streamedArchive{memArchive{"wide.png": data}, &n}
+
+	// Its size comes from the archive's listing
+	pages := Pages{{Path: "wide.png", Bytes: int64(len(data))}}
+	measurePages(a, pages)
+	require.Equal(t, Page{Path: "wide.png", Width: 40, Height: 20, Bytes: int64(len(data)), Wide: true}, pages[0])
+	require.LessOrEqual(t, n, int64(pageHeaderSize))
+}
+
+// Pages can only be read by walking the archive, and the walks are counted
+type walkedArchive struct {
+	memArchive
+	walks *int
+}
+
+func (a walkedArchive) read(p Page) ([]byte, error) {
+	return nil, errors.New("page read by itself")
+}
+
+func (a walkedArchive) walkFiles(fn func(name string, r io.Reader) error) error {
+	*a.walks++
+	for _, name := range slices.Sorted(maps.Keys(a.memArchive)) {
+		if err := fn(name, bytes.NewReader(a.memArchive[name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMeasurePages_Walked(t *testing.T) {
+	var walks int
+	a := walkedArchive{memArchive{
+		"tall.png":  mustEncodePNG(t, image.NewGray(image.Rect(0, 0, 10, 20))),
+		"wide.png":  mustEncodePNG(t, image.NewGray(image.Rect(0, 0, 40, 20))),
+		"notes.txt": []byte("not a page"),
+	}, &walks}
+
+	// Every page is measured in a single pass
+	pages := Pages{{Path: "wide.png"}, {Path: "tall.png"}}
+	measurePages(a, pages)
+	require.Equal(t, 1, walks)
+	require.Equal(t, Page{Path: "wide.png", Width: 40, Height: 20, Bytes: int64(len(a.memArchive["wide.png"])), Wide: true}, pages[0])
+	require.Equal(t, Page{Path: "tall.png", Width: 10, Height: 20, Bytes: int64(len(a.memArchive["tall.png"]))}, pages[1])
+}
@@ -0,0 +1,953 @@
+package tanuki
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// This is a minimal PDF reader, it only supports what's
+// needed to find the image embedded in each page of an
+// image-only PDF, i.e. the xref table (or stream), object
+// streams and the page tree
+
+var errUnsupportedPdf = fmt.Errorf("unsupported pdf")
+
+// Objects
+
+type pdfName string
+
+type pdfKeyword string
+
+type pdfDict map[pdfName]any
+
+type pdfArray []any
+
+type pdfRef struct {
+	Num, Gen int
+}
+
+type pdfStream struct {
+	Dict pdfDict
+	// Where the stream's data starts within
+	// the data it was parsed from
+	Offset int64
+}
+
+// Lexing
+
+type pdfLexer struct {
+	r      *bufio.Reader
+	pos    int64 // Offset of the next unread byte
+	peeked []any
+	depth  int // How deeply the object being parsed is nested
+}
+
+// Deeper objects are rejected so malformed
+// files can't exhaust the stack
+const pdfMaxDepth = 64
+
+func newPdfLexer(r io.ReaderAt, offset, size int64) *pdfLexer {
+	return &pdfLexer{
+		r:   bufio.NewReader(io.NewSectionReader(r, offset, size-offset)),
+		pos: offset,
+	}
+}
+
+func (l *pdfLexer) readByte() (byte, error) {
+	b, err := l.r.ReadByte()
+	if err == nil {
+		l.pos++
+	}
+	return b, err
+}
+
+func (l *pdfLexer) unreadByte() {
+	if err := l.r.UnreadByte(); err == nil {
+		l.pos--
+	}
+}
+
+func (l *pdfLexer) unread(tok any) {
+	l.peeked = append(l.peeked, tok)
+}
+
+func isPdfSpace(b byte) bool {
+	return b == 0 || b == '\t' || b == '\n' || b == '\f' || b == '\r' || b == ' '
+}
+
+func isPdfDelimiter(b byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), b) >= 0
+}
+
+// Returns the next token, which is either an int, float64,
+// string, pdfName or pdfKeyword. Dictionary and array
+// delimiters are returned as keywords
+func (l *pdfLexer) next() (any, error) {
+	if n := len(l.peeked); n > 0 {
+		tok := l.peeked[n-1]
+		l.peeked = l.peeked[:n-1]
+		return tok, nil
+	}
+
+	// Skip whitespace and comments
+	var b byte
+	var err error
+	for {
+		b, err = l.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == '%' {
+			for b != '\r' && b != '\n' {
+				if b, err = l.readByte(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if !isPdfSpace(b) {
+			break
+		}
+	}
+
+	switch b {
+	case '[', ']', '{', '}':
+		return pdfKeyword(b), nil
+	case '<':
+		b2, err := l.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if b2 == '<' {
+			return pdfKeyword("<<"), nil
+		}
+		l.unreadByte()
+		return l.hexString()
+	case '>':
+		if b2, err := l.readByte(); err != nil || b2 != '>' {
+			return nil, fmt.Errorf("unexpected '>' at offset %d", l.pos)
+		}
+		return pdfKeyword(">>"), nil
+	case '(':
+		return l.literalString()
+	case '/':
+		name, err := l.regular()
+		if err != nil {
+			return nil, err
+		}
+		return pdfName(decodePdfName(name)), nil
+	}
+
+	l.unreadByte()
+	word, err := l.regular()
+	if err != nil {
+		return nil, err
+	}
+	if i, err := strconv.Atoi(word); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(word, 64); err == nil {
+		return f, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+// Reads until the next whitespace or delimiter
+func (l *pdfLexer) regular() (string, error) {
+	var buf []byte
+	for {
+		b, err := l.readByte()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if isPdfSpace(b) || isPdfDelimiter(b) {
+			l.unreadByte()
+			break
+		}
+		buf = append(buf, b)
+	}
+	return string(buf), nil
+}
+
+func decodePdfName(s string) string {
+	if !bytes.ContainsRune([]byte(s), '#') {
+		return s
+	}
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && i+2 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				buf = append(buf, byte(v))
+				i += 2
+				continue
+			}
+		}
+		buf = append(buf, s[i])
+	}
+	return string(buf)
+}
+
+func (l *pdfLexer) hexString() (string, error) {
+	var digits []byte
+	for {
+		b, err := l.readByte()
+		if err != nil {
+			return "", err
+		}
+		if b == '>' {
+			break
+		}
+		if !isPdfSpace(b) {
+			digits = append(digits, b)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	buf := make([]byte, len(digits)/2)
+	for i := range buf {
+		v, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid hex string: %w", err)
+		}
+		buf[i] = byte(v)
+	}
+	return string(buf), nil
+}
+
+func (l *pdfLexer) literalString() (string, error) {
+	var buf []byte
+	depth := 1
+	for {
+		b, err := l.readByte()
+		if err != nil {
+			return "", err
+		}
+		switch b {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(buf), nil
+			}
+		case '\\':
+			// We don't care about the contents of strings, so
+			// escapes are only handled to find the string's end
+			if b, err = l.readByte(); err != nil {
+				return "", err
+			}
+		}
+		buf = append(buf, b)
+	}
+}
+
+// Parsing
+
+// Parses a single object, indirect references
+// are returned without being resolved
+func (l *pdfLexer) object() (any, error) {
+	tok, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case int:
+		// Integers may be the start of a "num gen R" reference
+		tok2, err := l.next()
+		if err != nil {
+			return t, nil
+		}
+		if gen, ok := tok2.(int); ok {
+			tok3, err := l.next()
+			if err == nil && tok3 == pdfKeyword("R") {
+				return pdfRef{t, gen}, nil
+			}
+			if err == nil {
+				l.unread(tok3)
+			}
+		}
+		l.unread(tok2)
+		return t, nil
+	case pdfKeyword:
+		if t == "<<" || t == "[" {
+			if l.depth >= pdfMaxDepth {
+				return nil, fmt.Errorf("object nested too deeply at offset %d", l.pos)
+			}
+			l.depth++
+			defer func() { l.depth-- }()
+		}
+		switch t {
+		case "<<":
+			d := make(pdfDict)
+			for {
+				key, err := l.next()
+				if err != nil {
+					return nil, err
+				}
+				if key == pdfKeyword(">>") {
+					return d, nil
+				}
+				name, ok := key.(pdfName)
+				if !ok {
+					return nil, fmt.Errorf("invalid dictionary key at offset %d", l.pos)
+				}
+				if d[name], err = l.object(); err != nil {
+					return nil, err
+				}
+			}
+		case "[":
+			a := make(pdfArray, 0)
+			for {
+				tok, err := l.next()
+				if err != nil {
+					return nil, err
+				}
+				if tok == pdfKeyword("]") {
+					return a, nil
+				}
+				l.unread(tok)
+				v, err := l.object()
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, v)
+			}
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("unexpected keyword %q at offset %d", t, l.pos)
+		}
+	default:
+		return tok, nil
+	}
+}
+
+// Parses a "num gen obj ... endobj" object, if the object is
+// a stream then only its dictionary and offset are returned
+func (l *pdfLexer) indirectObject(num int) (any, error) {
+	tok1, err1 := l.next()
+	tok2, err2 := l.next()
+	tok3, err3 := l.next()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, err
+	}
+	if _, isGen := tok2.(int); tok1 != num || !isGen || tok3 != pdfKeyword("obj") {
+		return nil, fmt.Errorf("object %d not found", num)
+	}
+
+	v, err := l.object()
+	if err != nil {
+		return nil, err
+	}
+	d, isDict := v.(pdfDict)
+	if !isDict {
+		return v, nil
+	}
+
+	tok, err := l.next()
+	if err != nil || tok != pdfKeyword("stream") {
+		return d, nil
+	}
+	// The stream keyword is followed by either CRLF or LF
+	b, err := l.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if b == '\r' {
+		if b, err = l.readByte(); err == nil && b != '\n' {
+			l.unreadByte()
+		}
+	} else if b != '\n' {
+		l.unreadByte()
+	}
+	return &pdfStream{Dict: d, Offset: l.pos}, nil
+}
+
+// Reader
+
+type pdfXrefEntry struct {
+	// For objects stored within an object stream this
+	// is the object's index within the stream
+	Offset int64
+	// The object stream the object is stored in,
+	// zero if the object is stored directly
+	Stream int
+	Free   bool
+}
+
+type pdfObjStm struct {
+	data    []byte
+	first   int64
+	offsets []int64
+}
+
+type pdfReader struct {
+	r       io.ReaderAt
+	size    int64
+	xref    map[int]pdfXrefEntry
+	trailer pdfDict
+	objects map[int]any
+	objStms map[int]*pdfObjStm
+	// Objects which are being read, malformed files may store
+	// an object stream within itself or within another stream
+	// which is stored within it
+	reading map[int]bool
+}
+
+func newPdfReader(r io.ReaderAt, size int64) (*pdfReader, error) {
+	p := &pdfReader{
+		r:       r,
+		size:    size,
+		xref:    make(map[int]pdfXrefEntry),
+		objects: make(map[int]any),
+		objStms: make(map[int]*pdfObjStm),
+		reading: make(map[int]bool),
+	}
+
+	// The offset of the last xref section is
+	// stored at the very end of the file
+	tail := make([]byte, min(size, 1024))
+	if _, err := r.ReadAt(tail, size-int64(len(tail))); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	i := bytes.LastIndex(tail, []byte("startxref"))
+	if i < 0 {
+		return nil, fmt.Errorf("startxref not found")
+	}
+	fields := bytes.Fields(tail[i+len("startxref"):])
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("startxref offset not found")
+	}
+	offset, err := strconv.ParseInt(string(fields[0]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid startxref offset: %w", err)
+	}
+
+	// Newer xref sections take priority and link to
+	// older ones, so we stop once we reach the oldest
+	visited := make(map[int64]bool)
+	for !visited[offset] {
+		visited[offset] = true
+		trailer, err := p.readXref(offset)
+		if err != nil {
+			return nil, fmt.Errorf("read xref: %w", err)
+		}
+		if p.trailer == nil {
+			p.trailer = trailer
+		}
+		// Hybrid files also store some objects in an xref stream
+		if stm, ok := trailer["XRefStm"].(int); ok && !visited[int64(stm)] {
+			visited[int64(stm)] = true
+			if _, err := p.readXref(int64(stm)); err != nil {
+				return nil, fmt.Errorf("read xref stream: %w", err)
+			}
+		}
+		prev, ok := trailer["Prev"].(int)
+		if !ok {
+			break
+		}
+		offset = int64(prev)
+	}
+
+	if _, encrypted := p.trailer["Encrypt"]; encrypted {
+		return nil, fmt.Errorf("%w: encrypted pdfs are not supported", errUnsupportedPdf)
+	}
+	return p, nil
+}
+
+func (p *pdfReader) addXref(num int, e pdfXrefEntry) {
+	if _, exists := p.xref[num]; !exists {
+		p.xref[num] = e
+	}
+}
+
+// Reads the xref section at the offset and returns its trailer
+func (p *pdfReader) readXref(offset int64) (pdfDict, error) {
+	l := newPdfLexer(p.r, offset, p.size)
+	tok, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+
+	// Xref tables
+	if tok == pdfKeyword("xref") {
+		for {
+			tok, err := l.next()
+			if err != nil {
+				return nil, err
+			}
+			if tok == pdfKeyword("trailer") {
+				v, err := l.object()
+				if err != nil {
+					return nil, err
+				}
+				trailer, ok := v.(pdfDict)
+				if !ok {
+					return nil, fmt.Errorf("invalid trailer")
+				}
+				return trailer, nil
+			}
+
+			start, ok1 := tok.(int)
+			tok, err = l.next()
+			count, ok2 := tok.(int)
+			if err != nil || !ok1 || !ok2 {
+				return nil, fmt.Errorf("invalid xref subsection")
+			}
+			for i := range count {
+				off, err1 := l.next()
+				_, err2 := l.next()
+				kind, err3 := l.next()
+				if err := errors.Join(err1, err2, err3); err != nil {
+					return nil, err
+				}
+				objOffset, ok := off.(int)
+				if !ok {
+					return nil, fmt.Errorf("invalid xref entry")
+				}
+				p.addXref(start+i, pdfXrefEntry{
+					Offset: int64(objOffset),
+					Free:   kind != pdfKeyword("n"),
+				})
+			}
+		}
+	}
+
+	// Xref streams
+	num, ok := tok.(int)
+	if !ok {
+		return nil, fmt.Errorf("xref not found at offset %d", offset)
+	}
+	l.unread(tok)
+	v, err := l.indirectObject(num)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.(*pdfStream)
+	if !ok || s.Dict["Type"] != pdfName("XRef") {
+		return nil, fmt.Errorf("xref not found at offset %d", offset)
+	}
+	data, err := p.streamData(s)
+	if err != nil {
+		return nil, err
+	}
+
+	w, ok := s.Dict["W"].(pdfArray)
+	if !ok || len(w) != 3 {
+		return nil, fmt.Errorf("invalid xref stream widths")
+	}
+	// Fields are read into int64s, so they're at most 8 bytes
+	widths := make([]int, 3)
+	for i := range w {
+		if widths[i], ok = w[i].(int); !ok || widths[i] < 0 || widths[i] > 8 {
+			return nil, fmt.Errorf("invalid xref stream widths")
+		}
+	}
+	index, ok := s.Dict["Index"].(pdfArray)
+	if !ok {
+		size, _ := s.Dict["Size"].(int)
+		index = pdfArray{0, size}
+	}
+
+	field := func(b []byte, width int, def int64) int64 {
+		if width == 0 {
+			return def
+		}
+		var v int64
+		for _, c := range b[:width] {
+			v = v<<8 | int64(c)
+		}
+		return v
+	}
+	entrySize := widths[0] + widths[1] + widths[2]
+	for i := 0; i+1 < len(index); i += 2 {
+		start, ok1 := index[i].(int)
+		count, ok2 := index[i+1].(int)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("invalid xref stream index")
+		}
+		for j := range count {
+			if len(data) < entrySize {
+				return nil, fmt.Errorf("xref stream too short")
+			}
+			kind := field(data, widths[0], 1)
+			a := field(data[widths[0]:], widths[1], 0)
+			b := field(data[widths[0]+widths[1]:], widths[2], 0)
+			data = data[entrySize:]
+
+			switch kind {
+			case 0:
+				p.addXref(start+j, pdfXrefEntry{Free: true})
+			case 1:
+				p.addXref(start+j, pdfXrefEntry{Offset: a})
+			case 2:
+				p.addXref(start+j, pdfXrefEntry{Offset: b, Stream: int(a)})
+			}
+		}
+	}
+
+	return s.Dict, nil
+}
+
+func (p *pdfReader) object(num int) (any, error) {
+	if v, found := p.objects[num]; found {
+		return v, nil
+	}
+
+	e, found := p.xref[num]
+	if !found || e.Free {
+		return nil, nil // Missing objects are treated as null
+	}
+	if p.reading[num] {
+		return nil, fmt.Errorf("object %d refers to itself", num)
+	}
+	p.reading[num] = true
+	defer delete(p.reading, num)
+
+	var v any
+	var err error
+	if e.Stream == 0 {
+		v, err = newPdfLexer(p.r, e.Offset, p.size).indirectObject(num)
+	} else {
+		v, err = p.objStmObject(e.Stream, e.Offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read object %d: %w", num, err)
+	}
+	p.objects[num] = v
+	return v, nil
+}
+
+func (p *pdfReader) objStmObject(num int, index int64) (any, error) {
+	stm, found := p.objStms[num]
+	if !found {
+		v, err := p.object(num)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(*pdfStream)
+		if !ok {
+			return nil, fmt.Errorf("object stream %d not found", num)
+		}
+		data, err := p.streamData(s)
+		if err != nil {
+			return nil, err
+		}
+		// Each object's number and offset take at
+		// least two bytes, including whitespace
+		n, ok1 := s.Dict["N"].(int)
+		first, ok2 := s.Dict["First"].(int)
+		if !ok1 || !ok2 || n < 0 || n > len(data)/2 || first < 0 || first > len(data) {
+			return nil, fmt.Errorf("invalid object stream %d", num)
+		}
+
+		// The stream starts with pairs of object
+		// numbers and their offsets
+		stm = &pdfObjStm{data: data, first: int64(first), offsets: make([]int64, n)}
+		l := newPdfLexer(bytes.NewReader(data), 0, int64(len(data)))
+		for i := range n {
+			_, err1 := l.next()
+			off, err2 := l.next()
+			if err := errors.Join(err1, err2); err != nil {
+				return nil, err
+			}
+			o, ok := off.(int)
+			if !ok || o < 0 {
+				return nil, fmt.Errorf("invalid object stream %d", num)
+			}
+			stm.offsets[i] = int64(o)
+		}
+		p.objStms[num] = stm
+	}
+
+	if index < 0 || index >= int64(len(stm.offsets)) {
+		return nil, fmt.Errorf("object index %d out of range", index)
+	}
+	size := int64(len(stm.data))
+	return newPdfLexer(bytes.NewReader(stm.data), stm.first+stm.offsets[index], size).object()
+}
+
+func (p *pdfReader) resolve(v any) (any, error) {
+	// References may point to other references
+	for range 32 {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v, nil
+		}
+		var err error
+		if v, err = p.object(ref.Num); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("reference chain too long")
+}
+
+func (p *pdfReader) dict(v any) (pdfDict, error) {
+	v, err := p.resolve(v)
+	if err != nil {
+		return nil, err
+	}
+	d, _ := v.(pdfDict)
+	return d, nil
+}
+
+// Returns where the stream's undecoded data is located in the file
+func (p *pdfReader) streamExtent(s *pdfStream) (int64, int64, error) {
+	v, err := p.resolve(s.Dict["Length"])
+	if err != nil {
+		return 0, 0, err
+	}
+	length, ok := v.(int)
+	if !ok || length < 0 || s.Offset+int64(length) > p.size {
+		return 0, 0, fmt.Errorf("invalid stream length")
+	}
+	return s.Offset, int64(length), nil
+}
+
+// Returns the stream's decoded data, only the filters
+// used for xref and object streams are supported
+func (p *pdfReader) streamData(s *pdfStream) ([]byte, error) {
+	offset, length, err := p.streamExtent(s)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, length)
+	if _, err := p.r.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+
+	filter, err := p.resolve(s.Dict["Filter"])
+	if err != nil {
+		return nil, err
+	}
+	switch f := filter.(type) {
+	case nil:
+		return data, nil
+	case pdfArray:
+		if len(f) == 0 {
+			return data, nil
+		}
+		if len(f) > 1 {
+			return nil, fmt.Errorf("multiple stream filters are not supported")
+		}
+		filter = f[0]
+	}
+	if filter != pdfName("FlateDecode") {
+		return nil, fmt.Errorf("stream filter %v is not supported", filter)
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	data, err = io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := p.dict(s.Dict["DecodeParms"])
+	if err != nil {
+		return nil, err
+	}
+	return unpredictPng(data, params)
+}
+
+// Xref streams are usually compressed using PNG predictors,
+// which need to be reversed before the stream can be read
+func unpredictPng(data []byte, params pdfDict) ([]byte, error) {
+	predictor, _ := params["Predictor"].(int)
+	if predictor < 10 {
+		return data, nil
+	}
+	columns, ok := params["Columns"].(int)
+	if !ok {
+		columns = 1
+	}
+	if columns < 1 || columns > len(data) {
+		return nil, fmt.Errorf("invalid png predictor columns %d", columns)
+	}
+
+	stride := columns + 1
+	out := make([]byte, 0, len(data)/stride*columns)
+	prev := make([]byte, columns)
+	for len(data) >= stride {
+		row := data[1:stride]
+		cur := make([]byte, columns)
+		for i := range row {
+			var left, upLeft byte
+			if i > 0 {
+				left, upLeft = cur[i-1], prev[i-1]
+			}
+			up := prev[i]
+
+			switch data[0] {
+			case 0:
+				cur[i] = row[i]
+			case 1:
+				cur[i] = row[i] + left
+			case 2:
+				cur[i] = row[i] + up
+			case 3:
+				cur[i] = row[i] + byte((int(left)+int(up))/2)
+			case 4:
+				cur[i] = row[i] + paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("invalid png predictor %d", data[0])
+			}
+		}
+		out = append(out, cur...)
+		prev = cur
+		data = data[stride:]
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	} else if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Pages
+
+// Returns the image of each page in order, we only
+// support PDFs whose pages consist of a single JPEG
+func (p *pdfReader) images() ([]archiveFile, error) {
+	root, err := p.dict(p.trailer["Root"])
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, fmt.Errorf("document catalog not found")
+	}
+
+	pages := make([]pdfDict, 0)
+	var walk func(v any, resources any, depth int) error
+	walk = func(v any, resources any, depth int) error {
+		node, err := p.dict(v)
+		if err != nil {
+			return err
+		}
+		// Malformed trees may contain cycles
+		if node == nil || depth > 64 {
+			return fmt.Errorf("invalid page tree")
+		}
+
+		// Resources are inherited from parent nodes
+		if r, found := node["Resources"]; found {
+			resources = r
+		}
+
+		if node["Type"] == pdfName("Page") {
+			page := make(pdfDict, len(node))
+			for k, v := range node {
+				page[k] = v
+			}
+			page["Resources"] = resources
+			pages = append(pages, page)
+			return nil
+		}
+
+		kids, err := p.resolve(node["Kids"])
+		if err != nil {
+			return err
+		}
+		kidsArr, _ := kids.(pdfArray)
+		for _, kid := range kidsArr {
+			if len(pages) > 100_000 {
+				return fmt.Errorf("too many pages")
+			}
+			if err := walk(kid, resources, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root["Pages"], nil, 0); err != nil {
+		return nil, err
+	}
+
+	files := make([]archiveFile, len(pages))
+	for i, page := range pages {
+		img, err := p.pageImage(page)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i+1, err)
+		}
+		offset, size, err := p.streamExtent(img)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i+1, err)
+		}
+		files[i] = archiveFile{
+			Name:   fmt.Sprintf("%d.jpg", i+1),
+			Offset: offset,
+			Size:   size,
+			Bytes:  size,
+		}
+	}
+	return files, nil
+}
+
+func (p *pdfReader) pageImage(page pdfDict) (*pdfStream, error) {
+	resources, err := p.dict(page["Resources"])
+	if err != nil {
+		return nil, err
+	}
+	xobjects, err := p.dict(resources["XObject"])
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]*pdfStream, 0, 1)
+	for _, v := range xobjects {
+		v, err := p.resolve(v)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := v.(*pdfStream); ok && s.Dict["Subtype"] == pdfName("Image") {
+			images = append(images, s)
+		}
+	}
+	if len(images) != 1 {
+		return nil, fmt.Errorf("%w: page has %d images, expected 1", errUnsupportedPdf, len(images))
+	}
+	img := images[0]
+
+	// The JPEG can be served as-is if it's the only filter
+	filter, err := p.resolve(img.Dict["Filter"])
+	if err != nil {
+		return nil, err
+	}
+	if a, ok := filter.(pdfArray); ok && len(a) == 1 {
+		filter = a[0]
+	}
+	if filter != pdfName("DCTDecode") {
+		return nil, fmt.Errorf("%w: page image is not a jpeg", errUnsupportedPdf)
+	}
+	return img, nil
+}
@@ -0,0 +1,120 @@
+package tanuki
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPdfLexer_Object(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"123", 123},
+		{"-1.5", -1.5},
+		{"/Name", pdfName("Name")},
+		{"/A#20B", pdfName("A B")},
+		{"(a (nested) \\) string)", "a (nested) ) string"},
+		{"<48656C6C6F>", "Hello"},
+		{"true", true},
+		{"null", nil},
+		{"1 0 R", pdfRef{1, 0}},
+		{"[1 2 0 R 3 /A]", pdfArray{1, pdfRef{2, 0}, 3, pdfName("A")}},
+		{"<< /A 1 /B [ (x) ] % comment\n /C << /D 4 5 R >> >>", pdfDict{
+			"A": 1,
+			"B": pdfArray{"x"},
+			"C": pdfDict{"D": pdfRef{4, 5}},
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			r := bytes.NewReader([]byte(tc.input))
+			v, err := newPdfLexer(r, 0, r.Size()).object()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestPdfLexer_IndirectObject(t *testing.T) {
+	input := "7 0 obj\n<< /Length 5 >>\nstream\r\nhello\nendstream\nendobj"
+	r := bytes.NewReader([]byte(input))
+
+	t.Run("stream", func(t *testing.T) {
+		v, err := newPdfLexer(r, 0, r.Size()).indirectObject(7)
+		require.NoError(t, err)
+		s, ok := v.(*pdfStream)
+		require.True(t, ok)
+		require.Equal(t, pdfDict{"Length": 5}, s.Dict)
+		require.Equal(t, "hello", input[s.Offset:s.Offset+5])
+	})
+
+	t.Run("wrong object", func(t *testing.T) {
+		_, err := newPdfLexer(r, 0, r.Size()).indirectObject(8)
+		require.Error(t, err)
+	})
+}
+
+func TestUnpredictPng(t *testing.T) {
+	// Two rows of two columns, the first uses no
+	// predictor and the second uses the "Up" predictor
+	data := []byte{0, 1, 2, 2, 1, 1}
+	out, err := unpredictPng(data, pdfDict{"Predictor": 12, "Columns": 2})
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 2, 3}, out)
+
+	out, err = unpredictPng(data, nil)
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+}
+
+func TestPdfReader_Malformed(t *testing.T) {
+	t.Run("xref stream widths", func(t *testing.T) {
+		input := "1 0 obj\n<< /Type /XRef /W [1 -5 1] /Size 1 /Length 3 >>\nstream\n\x01\x00\x00\nendstream\nendobj\n" +
+			"startxref\n0\n%%EOF"
+		r := bytes.NewReader([]byte(input))
+		_, err := newPdfReader(r, r.Size())
+		require.Error(t, err)
+	})
+
+	t.Run("object stream count", func(t *testing.T) {
+		input := "1 2"
+		r := bytes.NewReader([]byte(input))
+		p := &pdfReader{
+			r:       r,
+			size:    r.Size(),
+			objects: map[int]any{5: &pdfStream{Dict: pdfDict{"N": 1 << 40, "First": 0, "Length": 3}}},
+			objStms: make(map[int]*pdfObjStm),
+		}
+		_, err := p.objStmObject(5, 0)
+		require.Error(t, err)
+	})
+
+	t.Run("object stored within itself", func(t *testing.T) {
+		input := "1 0 obj\n<< /Type /XRef /W [1 1 1] /Size 3 /Index [2 1] /Root 2 0 R /Length 3 >>\n" +
+			"stream\n\x02\x02\x00\nendstream\nendobj\nstartxref\n0\n%%EOF"
+		r := bytes.NewReader([]byte(input))
+		p, err := newPdfReader(r, r.Size())
+		require.NoError(t, err)
+		_, err = p.images()
+		require.Error(t, err)
+	})
+
+	t.Run("deeply nested objects", func(t *testing.T) {
+		for _, open := range []string{"[", "<< /A "} {
+			r := bytes.NewReader(bytes.Repeat([]byte(open), 1<<16))
+			_, err := newPdfLexer(r, 0, r.Size()).object()
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("png predictor columns", func(t *testing.T) {
+		for _, columns := range []int{-1, 0, 1 << 40} {
+			_, err := unpredictPng([]byte{0, 1, 2}, pdfDict{"Predictor": 12, "Columns": columns})
+			require.Error(t, err)
+		}
+	})
+}
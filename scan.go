@@ -0,0 +1,398 @@
+package tanuki
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scans
+
+// Only one scan runs at a time, whether it was started
+// periodically, by the watcher or by an admin
+var (
+	errScanRunning = errors.New("a scan is already running")
+	errNoScan      = errors.New("no scan is running")
+	errNotSeries   = errors.New("not a series in the library")
+)
+
+// What's started a scan
+const (
+	scanPeriodic = "periodic"
+	scanWatch    = "watch"
+	scanManual   = "manual"
+)
+
+// The status of a scan, while it's running or after it's finished
+type ScanJob struct {
+	ID       int
+	Trigger  string   // What started the scan, either periodic, watch or manual
+	Paths    []string // Series which are being rescanned, empty for the whole library
+	Running  bool
+	Started  time.Time
+	Finished time.Time
+
+	// Progress of parsing the library, series count as
+	// processed once all of their entries have been parsed
+	Series       int
+	TotalSeries  int
+	Entries      int
+	TotalEntries int
+	Current      string        // The archive which was parsed last
+	Errors       []string      // Archives which couldn't be parsed so far
+	ETA          time.Duration // Estimated time until every entry's parsed
+
+	Result ScanResult // Only set once the scan's finished
+	Err    string     // Why the scan failed, if it did
+}
+
+type scanJob struct {
+	mu     sync.Mutex
+	status ScanJob
+	err    error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (j *scanJob) Status() ScanJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	st := j.status
+	st.Paths = slices.Clone(st.Paths)
+	st.Errors = slices.Clone(st.Errors)
+	// Reused entries are parsed much faster than new ones, so
+	// the estimate is rough until most of the library's parsed
+	if st.Running && st.Entries > 0 {
+		elapsed := time.Since(st.Started)
+		remaining := float64(st.TotalEntries-st.Entries) / float64(st.Entries)
+		st.ETA = time.Duration(float64(elapsed) * remaining).Round(time.Second)
+	}
+	return st
+}
+
+func (j *scanJob) running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status.Running
+}
+
+func (j *scanJob) report(p ParseProgress) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.status.Series, j.status.TotalSeries = p.Series, p.TotalSeries
+	j.status.Entries, j.status.TotalEntries = p.Entries, p.TotalEntries
+	if p.Path != "" {
+		j.status.Current = p.Path
+	}
+	if p.Err != nil {
+		j.status.Errors = append(j.status.Errors, fmt.Sprintf("%s: %s", p.Path, p.Err))
+	}
+}
+
+func (j *scanJob) rescanLibrary() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Paths = nil
+}
+
+func (j *scanJob) finish(r ScanResult, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.status.Running = false
+	j.status.Finished = time.Now()
+	j.status.Current = ""
+	j.status.Result = r
+	if err != nil {
+		j.status.Err = err.Error()
+	}
+	j.err = err
+}
+
+// Starts scanning the series at the paths, or the whole library if
+// there are none, unless a scan's already running. Scans run until
+// they finish or they're cancelled, e.g. by the server stopping
+func (s *Server) startScan(trigger string, paths []string) (*scanJob, error) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	if s.job != nil && s.job.running() {
+		return nil, errScanRunning
+	}
+	if err := s.scanCtx.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(s.scanCtx)
+	s.jobs++
+	j := &scanJob{
+		status: ScanJob{
+			ID:      s.jobs,
+			Trigger: trigger,
+			Paths:   paths,
+			Running: true,
+			Started: time.Now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.job = j
+
+	go func() {
+		defer close(j.done)
+		defer cancel()
+		r, err := s.runScan(ctx, j, trigger, paths)
+		j.finish(r, err)
+	}()
+	return j, nil
+}
+
+// Starts a scan and waits for it to finish. If a scan's already
+// running it's waited on instead, and if it didn't cover the paths
+// then a new scan is started once it's finished
+func (s *Server) waitScan(trigger string, paths []string) (*scanJob, error) {
+	for {
+		j, err := s.startScan(trigger, paths)
+		if errors.Is(err, errScanRunning) {
+			j = s.lastScan()
+			slog.Info("Waiting for running scan", slog.Int("id", j.Status().ID))
+			<-j.done
+			if !scanCovers(j.Status().Paths, paths) {
+				continue
+			}
+			return j, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		<-j.done
+		return j, nil
+	}
+}
+
+// Whether a scan of the scanned paths also scanned the paths,
+// no paths means the whole library
+func scanCovers(scanned, paths []string) bool {
+	if len(scanned) == 0 {
+		return true
+	}
+	if len(paths) == 0 {
+		return false
+	}
+	for _, p := range paths {
+		if !slices.Contains(scanned, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the running scan, or the last one if none are
+func (s *Server) lastScan() *scanJob {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	return s.job
+}
+
+func (s *Server) runScan(ctx context.Context, j *scanJob, trigger string, paths []string) (ScanResult, error) {
+	start := time.Now()
+	id := j.Status().ID
+	attrs := func(args ...any) []any {
+		return append([]any{slog.Int("id", id), slog.String("trigger", trigger)}, args...)
+	}
+
+	if len(paths) > 0 {
+		slog.Info("Rescanning series", attrs(slog.Any("paths", paths))...)
+		r, err := s.scanSeries(ctx, j, paths)
+		// Admins who asked for specific series are told they
+		// can't be rescanned, rather than scanning everything
+		if !errors.Is(err, errFullScanNeeded) || trigger == scanManual {
+			s.logScan("rescan series", start, r, err, attrs)
+			return r, err
+		}
+		slog.Info("Could not rescan series by themselves", attrs(slog.Any("err", err))...)
+		j.rescanLibrary()
+	}
+
+	slog.Info("Scanning library", attrs(slog.String("path", s.config.LibraryPath))...)
+	r, err := s.scanLibrary(ctx, j)
+	s.logScan("scan library", start, r, err, attrs)
+	return r, err
+}
+
+func (s *Server) logScan(action string, start time.Time, r ScanResult, err error, attrs func(...any) []any) {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		slog.Error("Partially failed to "+action, attrs(slog.Any("err", err))...)
+	} else if errors.Is(err, context.Canceled) {
+		slog.Info("Cancelled scan", attrs()...)
+		return
+	} else if err != nil {
+		slog.Error("Failed to "+action, attrs(slog.Any("err", err))...)
+		return
+	}
+	timeTaken := time.Since(start).Round(time.Millisecond)
+	slog.Info("Finished scan", attrs(slog.Duration("duration", timeTaken), r.Changes.attr())...)
+}
+
+// Entries which haven't changed since the last scan are reused,
+// so only new and changed archives are opened. Parse errors are
+// returned after the rest of the library's stored
+func (s *Server) scanLibrary(ctx context.Context, j *scanJob) (ScanResult, error) {
+	known, err := s.store.KnownEntries()
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("get known entries: %w", err)
+	}
+	opts := LibraryOptions{
+		OneShots: s.config.OneShots,
+		Known:    known,
+		Workers:  s.config.ScanWorkers,
+		Progress: j.report,
+	}
+	lib, parseErr := ParseLibraryContext(ctx, s.config.LibraryPath, opts)
+	var pe *ParseError
+	if parseErr != nil && !errors.As(parseErr, &pe) {
+		return ScanResult{}, parseErr
+	}
+
+	// An empty library is more likely to be a missing
+	// mount than a library whose every series was
+	// deleted, so the catalog's kept
+	if len(lib) == 0 {
+		return ScanResult{}, parseErr
+	}
+	r, err := s.populateCatalog(lib)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("populate catalog: %w", err)
+	}
+	return r, parseErr
+}
+
+// Series are targeted by their SID or by a path in their folder,
+// the folder they're in is the one which is rescanned
+func (s *Server) seriesFolder(target string) (string, error) {
+	root := s.libraryRoot()
+	sr, err := s.store.GetSeries(target)
+	if err == nil {
+		// Series which aren't in a folder of their own, e.g. the
+		// one-shots group which is the library's root, or whose
+		// path isn't known yet, can't be rescanned by themselves
+		if sr.Path == "" || filepath.Dir(sr.Path) != root {
+			return "", fmt.Errorf("%w: %s is not in a folder of its own", errFullScanNeeded, sr.Title)
+		}
+		return sr.Path, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	p, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%w: %s", errNotSeries, target)
+	}
+	name, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return filepath.Join(root, name), nil
+}
+
+// Series can only be rescanned by themselves if it doesn't affect
+// the rest of the catalog, i.e. they aren't duplicates of a series
+// which isn't being rescanned
+var errFullScanNeeded = errors.New("full scan needed")
+
+// Rescans the series at the paths, which are folders in the library's
+// root, and updates only them in the catalog. Series which no longer
+// exist are removed. Like full scans, parse errors are returned after
+// the rest of the series are stored
+func (s *Server) scanSeries(ctx context.Context, j *scanJob, paths []string) (ScanResult, error) {
+	known, err := s.store.KnownEntries(paths...)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("get known entries: %w", err)
+	}
+
+	var dirs []string
+	for _, p := range paths {
+		stat, err := os.Stat(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return ScanResult{}, err
+		} else if !stat.IsDir() {
+			return ScanResult{}, fmt.Errorf("%w: %s is not a series folder", errFullScanNeeded, p)
+		}
+		dirs = append(dirs, p)
+	}
+	opts := LibraryOptions{Known: known, Workers: s.config.ScanWorkers, Progress: j.report}
+	series, _, err := parseAll(ctx, dirs, nil, opts)
+	if err != nil {
+		return ScanResult{}, err
+	}
+
+	lib := make(map[Series][]Entry)
+	var pErr ParseError
+	for i, r := range series {
+		name := filepath.Base(dirs[i])
+		var sErr *ParseError
+		if errors.As(r.err, &sErr) {
+			for _, item := range sErr.Items {
+				pErr.Items = append(pErr.Items, ParseErrorItem{filepath.Join(name, item.Name), item.Err})
+			}
+		} else if r.err != nil {
+			pErr.Items = append(pErr.Items, ParseErrorItem{name, r.err})
+			continue
+		}
+		lib[r.Series] = r.entries
+	}
+
+	for sr := range lib {
+		stored, err := s.store.GetSeries(sr.SID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return ScanResult{}, err
+		}
+		if !slices.Contains(paths, stored.Path) {
+			return ScanResult{}, fmt.Errorf("%w: %s is a duplicate of %s", errFullScanNeeded, sr.Path, stored.Path)
+		}
+	}
+
+	lib, dups, err := s.store.ResolveDuplicates(lib)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	// Titles are unique, a full scan tells series
+	// which share one apart, see uniqueTitles
+	for sr := range lib {
+		other, err := s.store.seriesByTitle(sr.Title)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return ScanResult{}, err
+		}
+		if other.SID != sr.SID && !slices.Contains(paths, other.Path) {
+			return ScanResult{}, fmt.Errorf("%w: %s has the same title as %s", errFullScanNeeded, sr.Path, other.Path)
+		}
+	}
+	changes, err := s.store.UpdateSeries(paths, lib)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	r := ScanResult{Duplicates: dups, Changes: changes}
+	if len(pErr.Items) > 0 {
+		return r, &pErr
+	}
+	return r, nil
+}
@@ -0,0 +1,251 @@
+package tanuki
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanJob_Status(t *testing.T) {
+	j := &scanJob{status: ScanJob{Running: true, Started: time.Now().Add(-10 * time.Second)}}
+	j.report(ParseProgress{Series: 1, TotalSeries: 2, Entries: 1, TotalEntries: 3, Path: "a.zip"})
+	j.report(ParseProgress{Series: 1, TotalSeries: 2, Entries: 2, TotalEntries: 3, Path: "b.zip", Err: errUnsupportedPdf})
+
+	st := j.Status()
+	require.Equal(t, "b.zip", st.Current)
+	require.Equal(t, []string{"b.zip: " + errUnsupportedPdf.Error()}, st.Errors)
+	require.Equal(t, 5*time.Second, st.ETA)
+
+	// Finished scans have no estimate
+	j.finish(ScanResult{}, nil)
+	st = j.Status()
+	require.False(t, st.Running)
+	require.Zero(t, st.ETA)
+	require.Empty(t, st.Current)
+}
+
+func TestServer_ScanJobs(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.DataPath = t.TempDir()
+	conf.LibraryPath = "./tests/lib"
+	s, err := NewServer(conf)
+	require.NoError(t, err)
+	defer mustCloseStore(t, s.store)
+
+	require.ErrorIs(t, s.ScanStatus(struct{}{}, new(ScanJob)), errNoScan)
+	require.ErrorIs(t, s.CancelScan(struct{}{}, new(struct{})), errNoScan)
+
+	var r ScanResult
+	require.NoError(t, s.Scan(struct{}{}, &r))
+	require.Equal(t, CatalogChanges{Added: 5}, r.Changes)
+
+	var st ScanJob
+	require.NoError(t, s.ScanStatus(struct{}{}, &st))
+	require.Equal(t, 1, st.ID)
+	require.Equal(t, scanManual, st.Trigger)
+	require.False(t, st.Running)
+	require.Equal(t, 3, st.Series)
+	require.Equal(t, 3, st.TotalSeries)
+	require.Equal(t, 5, st.Entries)
+	require.Equal(t, 5, st.TotalEntries)
+	require.Equal(t, r, st.Result)
+
+	// Only one scan runs at a time
+	running := &scanJob{status: ScanJob{Running: true}}
+	s.job = running
+	require.ErrorIs(t, s.StartScan(struct{}{}, new(ScanJob)), errScanRunning)
+
+	// Cancelled scans don't touch the catalog
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.runScan(ctx, running, scanManual, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	ctl, err := s.store.GetCatalog()
+	require.NoError(t, err)
+	require.Len(t, ctl, 3)
+}
+
+func TestServer_Scan_Running(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.DataPath = t.TempDir()
+	conf.LibraryPath = "./tests/lib"
+	s, err := NewServer(conf)
+	require.NoError(t, err)
+	defer mustCloseStore(t, s.store)
+
+	scan := func() <-chan ScanResult {
+		results := make(chan ScanResult, 1)
+		go func() {
+			var r ScanResult
+			require.NoError(t, s.Scan(struct{}{}, &r))
+			results <- r
+		}()
+		require.Never(t, func() bool { return len(results) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+		return results
+	}
+
+	// Scans wait for the running library scan...
+	want := ScanResult{Changes: CatalogChanges{Unchanged: 7}}
+	running := &scanJob{status: ScanJob{Running: true}, done: make(chan struct{})}
+	s.job = running
+	results := scan()
+	running.finish(want, nil)
+	close(running.done)
+	require.Equal(t, want, <-results)
+
+	// ...but scan the library themselves after a series scan
+	running = &scanJob{status: ScanJob{Running: true, Paths: []string{"Akira"}}, done: make(chan struct{})}
+	s.job = running
+	results = scan()
+	running.finish(want, nil)
+	close(running.done)
+	require.Equal(t, CatalogChanges{Added: 5}, (<-results).Changes)
+}
+
+func TestScanCovers(t *testing.T) {
+	require.True(t, scanCovers(nil, nil))
+	require.True(t, scanCovers(nil, []string{"a"}))
+	require.True(t, scanCovers([]string{"a", "b"}, []string{"a"}))
+	require.False(t, scanCovers([]string{"a"}, nil))
+	require.False(t, scanCovers([]string{"a"}, []string{"b"}))
+}
+
+func TestServer_ScanSeries(t *testing.T) {
+	library := t.TempDir()
+	require.NoError(t, os.CopyFS(library, os.DirFS("tests/lib")))
+	conf := DefaultServerConfig()
+	conf.DataPath = t.TempDir()
+	conf.LibraryPath = library
+	s, err := NewServer(conf)
+	require.NoError(t, err)
+	defer mustCloseStore(t, s.store)
+	require.NoError(t, s.Scan(struct{}{}, new(ScanResult)))
+
+	catalog := func() int {
+		ctl, err := s.store.GetCatalog()
+		require.NoError(t, err)
+		return len(ctl)
+	}
+
+	// Series are targeted by their SID...
+	require.NoError(t, os.Remove(filepath.Join(library, "Akira", "Volume 02.zip")))
+	require.NoError(t, os.RemoveAll(filepath.Join(library, "Amano")))
+	var r ScanResult
+	require.NoError(t, s.ScanSeries(akiraSeries.SID, &r))
+	require.Equal(t, CatalogChanges{Removed: 1, Unchanged: 1}, r.Changes)
+	require.Equal(t, 3, catalog())
+
+	// ...or by a path in their folder
+	require.NoError(t, s.ScanSeries(filepath.Join(library, "Amano", "a.zip"), &r))
+	require.Equal(t, CatalogChanges{Removed: 1}, r.Changes)
+	require.Equal(t, 2, catalog())
+
+	// One-shots can only be rescanned with the rest of the library
+	oneShot := filepath.Join(library, "One Shot.zip")
+	require.NoError(t, os.Link(filepath.Join(library, "Akira", "Volume 01.zip"), oneShot))
+	require.ErrorIs(t, s.ScanSeries(oneShot, &r), errFullScanNeeded)
+	require.Equal(t, 2, catalog())
+
+	require.ErrorIs(t, s.ScanSeries(library, &r), errNotSeries)
+	require.ErrorIs(t, s.ScanSeries(t.TempDir(), &r), errNotSeries)
+}
+
+func TestServer_ScanSeries_SameTitle(t *testing.T) {
+	library := t.TempDir()
+	require.NoError(t, os.CopyFS(library, os.DirFS("tests/lib")))
+	volume := filepath.Join(library, "Akira", "Volume 01.zip")
+	require.NoError(t, os.Link(volume, filepath.Join(library, "Foo.zip")))
+	conf := DefaultServerConfig()
+	conf.DataPath = t.TempDir()
+	conf.LibraryPath = library
+	s, err := NewServer(conf)
+	require.NoError(t, err)
+	defer mustCloseStore(t, s.store)
+	require.NoError(t, s.Scan(struct{}{}, new(ScanResult)))
+
+	titles := func() []string {
+		ctl, err := s.store.GetCatalog()
+		require.NoError(t, err)
+		var v []string
+		for _, sr := range ctl {
+			v = append(v, sr.Title)
+		}
+		return v
+	}
+	require.Contains(t, titles(), "Foo")
+
+	// The new series has the one-shot's title, so
+	// only a full scan can tell them apart...
+	foo := filepath.Join(library, "Foo")
+	require.NoError(t, os.Mkdir(foo, 0o755))
+	require.NoError(t, os.Link(volume, filepath.Join(foo, "Volume 01.zip")))
+	require.ErrorIs(t, s.ScanSeries(foo, new(ScanResult)), errFullScanNeeded)
+	require.Len(t, titles(), 4)
+
+	// ...which the watcher falls back to
+	j, err := s.startScan(scanWatch, []string{foo})
+	require.NoError(t, err)
+	<-j.done
+	require.NoError(t, j.err)
+	require.Subset(t, titles(), []string{"Foo", "Foo (Foo.zip)"})
+	require.Len(t, titles(), 5)
+}
+
+func TestServer_ScanSeries_RelativeLibrary(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.DataPath = t.TempDir()
+	conf.LibraryPath = "./tests/lib"
+	s, err := NewServer(conf)
+	require.NoError(t, err)
+	defer mustCloseStore(t, s.store)
+	require.NoError(t, s.Scan(struct{}{}, new(ScanResult)))
+
+	var r ScanResult
+	require.NoError(t, s.ScanSeries("tests/lib/Akira/Volume 01.zip", &r))
+	require.Equal(t, CatalogChanges{Unchanged: 2}, r.Changes)
+
+	akira, err := filepath.Abs("tests/lib/Akira")
+	require.NoError(t, err)
+	var st ScanJob
+	require.NoError(t, s.ScanStatus(struct{}{}, &st))
+	require.Equal(t, []string{akira}, st.Paths)
+}
+
+func TestServer_ScanSeries_NotInFolder(t *testing.T) {
+	library := t.TempDir()
+	require.NoError(t, os.CopyFS(library, os.DirFS("tests/lib")))
+	oneShot := filepath.Join(library, "One Shot.zip")
+	require.NoError(t, os.Link(filepath.Join(library, "Akira", "Volume 01.zip"), oneShot))
+	conf := DefaultServerConfig()
+	conf.DataPath = t.TempDir()
+	conf.LibraryPath = library
+	conf.OneShots = "One-shots"
+	s, err := NewServer(conf)
+	require.NoError(t, err)
+	defer mustCloseStore(t, s.store)
+	require.NoError(t, s.Scan(struct{}{}, new(ScanResult)))
+
+	catalog := func() int {
+		ctl, err := s.store.GetCatalog()
+		require.NoError(t, err)
+		return len(ctl)
+	}
+	require.Equal(t, 4, catalog())
+
+	// The one-shots group's path is the library's root
+	var r ScanResult
+	require.ErrorIs(t, s.ScanSeries(Sha256("One-shots/"), &r), errFullScanNeeded)
+	require.Equal(t, 4, catalog())
+
+	// Series stored before their paths were don't have one
+	_, err = s.store.pool.Exec(`UPDATE series SET path = '' WHERE sid = ?`, akiraSeries.SID)
+	require.NoError(t, err)
+	require.ErrorIs(t, s.ScanSeries(akiraSeries.SID, &r), errFullScanNeeded)
+	_, err = s.store.UpdateSeries([]string{""}, nil)
+	require.Error(t, err)
+	require.Equal(t, 4, catalog())
+}
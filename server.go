@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -15,8 +16,10 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -34,17 +37,28 @@ type ServerConfig struct {
 	LibraryPath  string   `toml:"library_path"`
 	ScanInterval duration `toml:"scan_interval"`
 	LogLevel     string   `toml:"log_level"`
+	OneShots     string   `toml:"one_shots"`
+	ScanWorkers  int      `toml:"scan_workers"` // How many archives are parsed at once
+	// Watching the library rescans the series which change
+	// once they've stopped changing for the watch delay, or
+	// once the max delay has passed since they first changed
+	Watch         bool     `toml:"watch"`
+	WatchDelay    duration `toml:"watch_delay"`
+	WatchMaxDelay duration `toml:"watch_max_delay"`
 }
 
 func DefaultServerConfig() ServerConfig {
 	return ServerConfig{
-		Host:         "0.0.0.0",
-		HttpPort:     8001,
-		RpcPort:      9001,
-		DataPath:     "./data",
-		LibraryPath:  "./library",
-		ScanInterval: duration{1 * time.Hour},
-		LogLevel:     "DEBUG",
+		Host:          "0.0.0.0",
+		HttpPort:      8001,
+		RpcPort:       9001,
+		DataPath:      "./data",
+		LibraryPath:   "./library",
+		ScanInterval:  duration{1 * time.Hour},
+		LogLevel:      "DEBUG",
+		WatchDelay:    duration{10 * time.Second},
+		WatchMaxDelay: duration{5 * time.Minute},
+		ScanWorkers:   runtime.NumCPU(),
 	}
 }
 
@@ -61,6 +75,11 @@ type Server struct {
 	privateH *rpc.Server
 
 	// Long-running tasks accounting
+	scanCtx       context.Context // Cancelled to stop scans mid-way
+	cancelScan    context.CancelFunc
+	scanMu        sync.Mutex
+	job           *scanJob // The running scan, or the last one
+	jobs          int
 	stopScan      chan struct{}
 	ackStopScan   chan struct{}
 	stopVacuum    chan struct{}
@@ -81,6 +100,7 @@ func NewServer(config ServerConfig) (*Server, error) {
 		return nil, fmt.Errorf("create store: %w", err)
 	}
 
+	scanCtx, cancelScan := context.WithCancel(context.Background())
 	s := &Server{
 		config: config,
 		store:  store,
@@ -89,6 +109,8 @@ func NewServer(config ServerConfig) (*Server, error) {
 			Handler: router(store),
 		},
 		privateH:      rpc.NewServer(),
+		scanCtx:       scanCtx,
+		cancelScan:    cancelScan,
 		stopScan:      make(chan struct{}),
 		ackStopScan:   make(chan struct{}),
 		stopVacuum:    make(chan struct{}),
@@ -181,9 +203,14 @@ func (s *Server) Stop() {
 		slog.Info("Server stopped")
 	}()
 
-	// Stop long-running tasks
+	// Stop long-running tasks, scans which are in
+	// progress are cancelled rather than finished
+	s.cancelScan()
 	s.stopScan <- struct{}{}
 	<-s.ackStopScan
+	if j := s.lastScan(); j != nil {
+		<-j.done
+	}
 	close(s.stopScan)
 	close(s.ackStopScan)
 	s.stopVacuum <- struct{}{}
@@ -209,10 +236,18 @@ func router(s *Store) *chi.Mux {
 
 		r.Get("/search", handleSearch())
 		r.Get("/catalog", handleCatalog(s))
+		r.Get("/tags", handleTags(s))
+		r.Get("/tags/{tid}", handleTag(s))
+		r.Get("/creators", handleCreators(s))
+		r.Get("/creators/{cid}", handleCreator(s))
 		r.Get("/series/{sid}", handleEntries(s))
+		r.Get("/series/{sid}/all", handleAllEntries(s))
+		r.Get("/series/{sid}/folders/{fid}", handleFolder(s))
+		r.Get("/series/{sid}/cover", handleSeriesCover(s))
 		r.Get("/series/{sid}/entries/{eid}/archive", handleArchive(s))
 		r.Get("/series/{sid}/entries/{eid}/cover", handleCover(s))
 		r.Get("/series/{sid}/entries/{eid}/page/{num}", handlePage(s))
+		r.Get("/series/{sid}/entries/{eid}/pages", handlePages(s))
 	})
 
 	return r
@@ -221,41 +256,82 @@ func router(s *Store) *chi.Mux {
 // Tasks
 
 func (s *Server) scan() {
-	task := func() {
-		slog.Info("Scanning library", slog.String("path", s.config.LibraryPath))
+	task := func(trigger string) {
+		if _, err := s.startScan(trigger, nil); errors.Is(err, errScanRunning) {
+			slog.Info("Skipping scan, another scan is running", slog.String("trigger", trigger))
+		} else if err != nil {
+			slog.Error("Failed to start scan", slog.Any("err", err))
+		}
+	}
 
-		start := time.Now()
+	t := time.NewTicker(s.config.ScanInterval.Duration)
 
-		lib, err := ParseLibrary(s.config.LibraryPath)
+	// Periodic scans still happen while the library's watched,
+	// in case the watcher misses anything, e.g. changes made
+	// by other machines to a network filesystem
+	var w watcher
+	var events <-chan string
+	var errs <-chan error
+	if s.config.Watch {
+		var err error
+		w, err = newWatcher(s.libraryRoot())
 		if err != nil {
-			var pe *ParseError
-			if errors.As(err, &pe) {
-				slog.Error("Partially failed to scan library", slog.Any("err", err))
-			} else {
-				slog.Error("Failed to scan library", slog.Any("err", err))
-				return
-			}
+			slog.Warn("Failed to watch library, falling back to periodic scans", slog.Any("err", err))
+		} else {
+			slog.Info("Watching library", slog.String("path", s.config.LibraryPath))
+			events, errs = w.Events(), w.Errors()
 		}
-
-		if len(lib) >= 1 {
-			if err := s.store.PopulateCatalog(lib); err != nil {
-				slog.Error("Failed to populate catalog", slog.Any("err", err))
-			} else {
-				timeTaken := time.Since(start).Round(time.Millisecond)
-				slog.Info("Scanned library", slog.Duration("duration", timeTaken))
+	}
+	stopWatching := func() {
+		if w != nil {
+			if err := w.Close(); err != nil {
+				slog.Error("Failed to stop watching library", slog.Any("err", err))
 			}
+			w, events, errs = nil, nil, nil
 		}
 	}
 
-	t := time.NewTicker(s.config.ScanInterval.Duration)
+	// Changes are debounced, so a chapter which is being
+	// copied is only rescanned once it's been copied
+	debounce := time.NewTimer(s.config.WatchDelay.Duration)
+	debounce.Stop()
+	var changed []string
+	var firstChange time.Time
 
-	task() // We want to scan on startup
+	task(scanPeriodic) // We want to scan on startup
 	for {
 		select {
 		case <-t.C:
-			task()
+			task(scanPeriodic)
+		case p := <-events:
+			if len(changed) == 0 {
+				firstChange = time.Now()
+			}
+			changed = append(changed, p)
+			debounce.Reset(debounceDelay(s.config.WatchDelay.Duration, s.config.WatchMaxDelay.Duration,
+				time.Since(firstChange)))
+		case <-debounce.C:
+			paths, full := changedSeries(s.libraryRoot(), changed)
+			if full {
+				paths = nil
+			}
+			_, err := s.startScan(scanWatch, paths)
+			if errors.Is(err, errScanRunning) {
+				// The changes are kept until the
+				// running scan has finished
+				debounce.Reset(s.config.WatchDelay.Duration)
+				continue
+			} else if err != nil {
+				slog.Error("Failed to start scan", slog.Any("err", err))
+			}
+			changed = nil
+		case err := <-errs:
+			slog.Warn("Stopped watching library, falling back to periodic scans", slog.Any("err", err))
+			stopWatching()
 		case <-s.stopScan:
 			t.Stop()
+			debounce.Stop()
+			stopWatching()
 			slog.Info("Done scanning")
 			s.ackStopScan <- struct{}{}
 			return
@@ -263,6 +339,45 @@ func (s *Server) scan() {
 	}
 }
 
+// Series which keep changing, e.g. because files are continually
+// being added to them, are still rescanned once the max delay has
+// passed since they first changed. The max delay is ignored if
+// it isn't set
+func debounceDelay(delay, maxDelay, sinceFirst time.Duration) time.Duration {
+	if maxDelay <= 0 {
+		return delay
+	}
+	return max(min(delay, maxDelay-sinceFirst), 0)
+}
+
+func (s *Server) libraryRoot() string {
+	root, err := filepath.Abs(s.config.LibraryPath)
+	if err != nil {
+		return s.config.LibraryPath
+	}
+	return root
+}
+
+// Duplicates are resolved before the library's stored, the
+// ones which haven't been resolved yet are logged every scan
+func (s *Server) populateCatalog(lib map[Series][]Entry) (ScanResult, error) {
+	lib, dups, err := s.store.ResolveDuplicates(lib)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	for _, d := range dups {
+		if !d.Resolved {
+			slog.Warn("Found duplicates", slog.String("sid", d.SID), slog.String("eid", d.EID),
+				slog.Any("paths", d.Paths))
+		}
+	}
+	changes, err := s.store.UpdateCatalog(lib)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	return ScanResult{Duplicates: dups, Changes: changes}, nil
+}
+
 func (s *Server) vacuum() {
 	t := time.NewTicker(24 * time.Hour)
 
@@ -288,6 +403,11 @@ func (s *Server) vacuum() {
 
 const opdsMime = "application/atom+xml"
 
+var catalogAuthor = opdsAuthor{
+	Name: "fiwippi",
+	URI:  "https://github.com/fiwippi",
+}
+
 func handleSearch() http.HandlerFunc {
 	// Pre-encode the search-related XML
 	// since it remains static
@@ -319,19 +439,24 @@ func handleCatalog(s *Store) http.HandlerFunc {
 			}
 		}
 
-		c := newOpdsFeed("ctl", "Catalog", modTime, opdsAuthor{
-			Name: "fiwippi",
-			URI:  "https://github.com/fiwippi",
-		})
+		c := newOpdsFeed("ctl", "Catalog", modTime, catalogAuthor)
 		c.addLink("/catalog", relSelf, typeNavigation)
 		c.addLink("/search", relSearch, typeSearch)
+		c.addLink("/tags", relSubsection, typeNavigation)
+		c.addLink("/creators", relSubsection, typeNavigation)
 
+		coverTypes, err := s.GetSeriesCoverTypes()
+		if err != nil {
+			slog.Error("Failed to retrieve cover types", slog.Any("err", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 		filter := r.URL.Query().Get("search")
 		for _, series := range catalog {
 			if len(filter) > 0 && !fuzzy(series.Title, filter) {
 				continue
 			}
-			c.addSeries(&series)
+			c.addSeries(&series, coverTypes[series.SID])
 		}
 
 		w.Header().Set("Content-Type", opdsMime)
@@ -343,6 +468,154 @@ func handleCatalog(s *Store) http.HandlerFunc {
 	}
 }
 
+func handleTags(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tags, err := s.GetTags()
+		if err != nil {
+			slog.Error("Failed to retrieve tags", slog.Any("err", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var modTime time.Time
+		for _, t := range tags {
+			if t.ModTime.After(modTime) {
+				modTime = t.ModTime
+			}
+		}
+
+		c := newOpdsFeed("tags", "Tags", modTime, catalogAuthor)
+		c.addLink("/tags", relSelf, typeNavigation)
+		for _, t := range tags {
+			c.addTag(&t)
+		}
+
+		w.Header().Set("Content-Type", opdsMime)
+		w.WriteHeader(http.StatusOK)
+		if err := newXmlEncoder(w).Encode(c); err != nil {
+			slog.Error("Failed to encode tags", slog.Any("err", err))
+			return
+		}
+	}
+}
+
+func handleTag(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tid := r.PathValue("tid")
+		if tid == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		tag, err := s.GetTag(tid)
+		if err != nil {
+			slog.Error("Failed to retrieve tag", slog.Any("err", err), slog.String("tid", tid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		series, err := s.GetTaggedSeries(tid)
+		if err != nil {
+			slog.Error("Failed to retrieve tagged series", slog.Any("err", err), slog.String("tid", tid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		coverTypes, err := s.GetSeriesCoverTypes()
+		if err != nil {
+			slog.Error("Failed to retrieve cover types", slog.Any("err", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		c := newOpdsFeed(tag.TID, tag.Name, tag.ModTime, catalogAuthor)
+		c.addLink("/tags/"+tag.TID, relSelf, typeNavigation)
+		for _, sr := range series {
+			c.addSeries(&sr, coverTypes[sr.SID])
+		}
+
+		w.Header().Set("Content-Type", opdsMime)
+		w.WriteHeader(http.StatusOK)
+		if err := newXmlEncoder(w).Encode(c); err != nil {
+			slog.Error("Failed to encode tag", slog.Any("err", err), slog.String("tid", tid))
+			return
+		}
+	}
+}
+
+func handleCreators(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		creators, err := s.GetCreators()
+		if err != nil {
+			slog.Error("Failed to retrieve creators", slog.Any("err", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var modTime time.Time
+		for _, c := range creators {
+			if c.ModTime.After(modTime) {
+				modTime = c.ModTime
+			}
+		}
+
+		c := newOpdsFeed("creators", "Creators", modTime, catalogAuthor)
+		c.addLink("/creators", relSelf, typeNavigation)
+		for _, cr := range creators {
+			c.addCreator(&cr)
+		}
+
+		w.Header().Set("Content-Type", opdsMime)
+		w.WriteHeader(http.StatusOK)
+		if err := newXmlEncoder(w).Encode(c); err != nil {
+			slog.Error("Failed to encode creators", slog.Any("err", err))
+			return
+		}
+	}
+}
+
+func handleCreator(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cid := r.PathValue("cid")
+		if cid == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		creator, err := s.GetCreator(cid)
+		if err != nil {
+			slog.Error("Failed to retrieve creator", slog.Any("err", err), slog.String("cid", cid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		series, err := s.GetCreatorSeries(cid)
+		if err != nil {
+			slog.Error("Failed to retrieve creator's series", slog.Any("err", err), slog.String("cid", cid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		coverTypes, err := s.GetSeriesCoverTypes()
+		if err != nil {
+			slog.Error("Failed to retrieve cover types", slog.Any("err", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		c := newOpdsFeed(creator.CID, creator.Name, creator.ModTime, catalogAuthor)
+		c.addLink("/creators/"+creator.CID, relSelf, typeNavigation)
+		for _, sr := range series {
+			c.addSeries(&sr, coverTypes[sr.SID])
+		}
+
+		w.Header().Set("Content-Type", opdsMime)
+		w.WriteHeader(http.StatusOK)
+		if err := newXmlEncoder(w).Encode(c); err != nil {
+			slog.Error("Failed to encode creator", slog.Any("err", err), slog.String("cid", cid))
+			return
+		}
+	}
+}
+
 func handleEntries(s *Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		sid := r.PathValue("sid")
@@ -366,6 +639,52 @@ func handleEntries(s *Store) http.HandlerFunc {
 
 		c := newOpdsFeed(series.SID, series.Title, series.ModTime, opdsAuthor{Name: series.Author})
 		c.addLink("/series/"+series.SID, relSelf, typeAcquisition)
+
+		// Subfolders are listed before the entries in the
+		// series' root, along with a flat view of every entry
+		folders := subfolders(entries, "")
+		if len(folders) > 0 {
+			c.addAllEntries(&series, len(entries))
+		}
+		for _, fd := range folders {
+			c.addFolder(&fd)
+		}
+		for _, e := range folderEntries(entries, "") {
+			c.addEntry(&e)
+		}
+
+		w.Header().Set("Content-Type", opdsMime)
+		w.WriteHeader(http.StatusOK)
+		if err := newXmlEncoder(w).Encode(c); err != nil {
+			slog.Error("Failed to encode entries", slog.Any("err", err), slog.String("sid", sid))
+			return
+		}
+	}
+}
+
+func handleAllEntries(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sid := r.PathValue("sid")
+		if sid == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		series, err := s.GetSeries(sid)
+		if err != nil {
+			slog.Error("Failed to retrieve series", slog.Any("err", err), slog.String("sid", sid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		entries, err := s.GetEntries(sid)
+		if err != nil {
+			slog.Error("Failed to retrieve entries", slog.Any("err", err), slog.String("sid", sid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		c := newOpdsFeed(series.SID+"-all", series.Title, series.ModTime, opdsAuthor{Name: series.Author})
+		c.addLink("/series/"+series.SID+"/all", relSelf, typeAcquisition)
 		for _, e := range entries {
 			c.addEntry(&e)
 		}
@@ -379,6 +698,52 @@ func handleEntries(s *Store) http.HandlerFunc {
 	}
 }
 
+func handleFolder(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sid := r.PathValue("sid")
+		fid := r.PathValue("fid")
+		if sid == "" || fid == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		series, err := s.GetSeries(sid)
+		if err != nil {
+			slog.Error("Failed to retrieve series", slog.Any("err", err), slog.String("sid", sid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		folder, err := s.GetFolder(sid, fid)
+		if err != nil {
+			slog.Error("Failed to retrieve folder", slog.Any("err", err), slog.String("sid", sid), slog.String("fid", fid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		entries, err := s.GetEntries(sid)
+		if err != nil {
+			slog.Error("Failed to retrieve entries", slog.Any("err", err), slog.String("sid", sid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		c := newOpdsFeed(folder.FID, series.Title+" / "+folder.Path, folder.ModTime, opdsAuthor{Name: series.Author})
+		c.addLink("/series/"+series.SID+"/folders/"+folder.FID, relSelf, typeAcquisition)
+		for _, fd := range subfolders(entries, folder.Path) {
+			c.addFolder(&fd)
+		}
+		for _, e := range folderEntries(entries, folder.Path) {
+			c.addEntry(&e)
+		}
+
+		w.Header().Set("Content-Type", opdsMime)
+		w.WriteHeader(http.StatusOK)
+		if err := newXmlEncoder(w).Encode(c); err != nil {
+			slog.Error("Failed to encode folder", slog.Any("err", err), slog.String("sid", sid), slog.String("fid", fid))
+			return
+		}
+	}
+}
+
 func handleArchive(s *Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		sid := r.PathValue("sid")
@@ -395,6 +760,18 @@ func handleArchive(s *Store) http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+
+		// Folders of images are zipped on the fly
+		if stat, err := os.Stat(entry.Archive); err == nil && stat.IsDir() {
+			filename := url.QueryEscape(filepath.Base(entry.Archive) + ".zip")
+			w.Header().Set("Content-Type", dirType.Mime)
+			w.Header().Set("Content-Disposition", `attachment; filename*=UTF-8''`+filename)
+			if err := writeDirZip(w, entry.Archive, entry.Pages); err != nil {
+				slog.Error("Failed to zip folder", slog.Any("err", err),
+					slog.String("sid", sid), slog.String("eid", eid))
+			}
+			return
+		}
 		sendFileAsAttachment(w, r, entry.Archive)
 	}
 }
@@ -415,7 +792,7 @@ func handleCover(s *Store) http.HandlerFunc {
 		if thumbnail {
 			cover, mime, err = s.GetThumbnail(sid, eid)
 		} else {
-			cover, mime, err = s.GetPage(sid, eid, 0)
+			cover, mime, err = s.GetCover(sid, eid)
 		}
 		if err != nil {
 			slog.Error("Failed to retrieve cover", slog.Any("err", err),
@@ -427,6 +804,32 @@ func handleCover(s *Store) http.HandlerFunc {
 	}
 }
 
+func handleSeriesCover(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sid := r.PathValue("sid")
+		if sid == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		thumbnail := r.URL.Query().Get("thumbnail") == "true"
+
+		var err error
+		var mime string
+		var cover *bytes.Buffer
+		if thumbnail {
+			cover, mime, err = s.GetSeriesThumbnail(sid)
+		} else {
+			cover, mime, err = s.GetSeriesCover(sid)
+		}
+		if err != nil {
+			slog.Error("Failed to retrieve series cover", slog.Any("err", err), slog.String("sid", sid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		sendFile(w, cover, mime)
+	}
+}
+
 func handlePage(s *Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		sid := r.PathValue("sid")
@@ -452,33 +855,140 @@ func handlePage(s *Store) http.HandlerFunc {
 	}
 }
 
+// Page metadata is served as JSON since OPDS has no way to describe it
+type pageInfo struct {
+	Number int    `json:"number"`
+	Mime   string `json:"mime"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Bytes  int64  `json:"bytes"`
+	Wide   bool   `json:"wide"`
+}
+
+func handlePages(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sid := r.PathValue("sid")
+		eid := r.PathValue("eid")
+		if sid == "" || eid == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		e, err := s.GetEntry(sid, eid)
+		if err != nil {
+			slog.Error("Failed to retrieve entry", slog.Any("err", err), slog.String("sid", sid), slog.String("eid", eid))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		pages := make([]pageInfo, len(e.Pages))
+		for i, p := range e.Pages {
+			pages[i] = pageInfo{Number: i, Mime: p.Mime, Width: p.Width, Height: p.Height, Bytes: p.Bytes, Wide: p.Wide}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(pages); err != nil {
+			slog.Error("Failed to encode pages", slog.Any("err", err), slog.String("sid", sid), slog.String("eid", eid))
+			return
+		}
+	}
+}
+
 // RPCs
 
-func (s *Server) Scan(_ struct{}, _ *struct{}) error {
+type ScanResult struct {
+	Duplicates []Duplicate
+	Changes    CatalogChanges
+}
+
+// Scans the library and waits for the scan to finish. If a scan's
+// already running then it's waited on instead. Parse errors are
+// returned so the RPC client can inspect them, even though the
+// rest of the library was stored
+func (s *Server) Scan(_ struct{}, result *ScanResult) error {
 	slog.Info("Manually scanning library")
+	j, err := s.waitScan(scanManual, nil)
+	if err != nil {
+		return err
+	}
+	*result = j.Status().Result
+	return j.err
+}
 
-	lib, err := ParseLibrary(s.config.LibraryPath)
+// Starts scanning the library without waiting for it to finish
+func (s *Server) StartScan(_ struct{}, job *ScanJob) error {
+	slog.Info("Manually starting scan")
+	j, err := s.startScan(scanManual, nil)
 	if err != nil {
-		var pe *ParseError
-		if errors.As(err, &pe) {
-			// We still try to populate the store but
-			// return the error at the end so the RPC
-			// client can inspect the parse failures
-			slog.Error("Partially failed to manually scan library", slog.Any("err", err))
-		} else {
-			return err
-		}
+		return err
 	}
+	*job = j.Status()
+	return nil
+}
 
-	if len(lib) >= 1 {
-		if err := s.store.PopulateCatalog(lib); err != nil {
-			slog.Error("Failed to populate manually scanned catalog", slog.Any("err", err))
-			return err
-		}
+// Rescans a single series and waits for it to finish, the rest of
+// the catalog isn't touched. The series is identified by its SID or
+// by a path in its folder
+func (s *Server) ScanSeries(target string, result *ScanResult) error {
+	slog.Info("Manually scanning series", slog.String("target", target))
+	path, err := s.findSeriesFolder(target)
+	if err != nil {
+		return err
+	}
+	j, err := s.waitScan(scanManual, []string{path})
+	if err != nil {
+		return err
 	}
+	*result = j.Status().Result
+	return j.err
+}
 
-	slog.Info("Manual scan complete")
-	return err
+// Starts rescanning a single series without waiting for it to finish
+func (s *Server) StartScanSeries(target string, job *ScanJob) error {
+	slog.Info("Manually starting series scan", slog.String("target", target))
+	j, err := s.startSeriesScan(target)
+	if err != nil {
+		return err
+	}
+	*job = j.Status()
+	return nil
+}
+
+func (s *Server) startSeriesScan(target string) (*scanJob, error) {
+	path, err := s.findSeriesFolder(target)
+	if err != nil {
+		return nil, err
+	}
+	return s.startScan(scanManual, []string{path})
+}
+
+func (s *Server) findSeriesFolder(target string) (string, error) {
+	path, err := s.seriesFolder(target)
+	if err != nil {
+		slog.Error("Failed to find series", slog.Any("err", err), slog.String("target", target))
+		return "", err
+	}
+	return path, nil
+}
+
+// Returns the status of the running scan, or the last one
+func (s *Server) ScanStatus(_ struct{}, job *ScanJob) error {
+	j := s.lastScan()
+	if j == nil {
+		return errNoScan
+	}
+	*job = j.Status()
+	return nil
+}
+
+func (s *Server) CancelScan(_ struct{}, _ *struct{}) error {
+	j := s.lastScan()
+	if j == nil || !j.running() {
+		return errNoScan
+	}
+	slog.Info("Cancelling scan", slog.Int("id", j.Status().ID))
+	j.cancel()
+	return nil
 }
 
 func (s *Server) Dump(_ struct{}, output *string) error {
@@ -493,6 +1003,16 @@ func (s *Server) Dump(_ struct{}, output *string) error {
 	return nil
 }
 
+func (s *Server) SchemaVersion(_ struct{}, version *int) error {
+	v, err := s.store.SchemaVersion()
+	if err != nil {
+		slog.Error("Failed to get schema version", slog.Any("err", err))
+		return err
+	}
+	*version = v
+	return nil
+}
+
 func (s *Server) AddUser(u User, _ *struct{}) error {
 	log := slog.With(slog.String("name", u.Name))
 
@@ -549,6 +1069,94 @@ func (s *Server) ChangePassword(req ChangePasswordRequest, _ *struct{}) error {
 	return nil
 }
 
+// Values can be empty, the override's
+// only removed if remove is set
+type EditSeriesRequest struct {
+	SID, Field, Value string
+	Remove            bool
+}
+
+func (s *Server) EditSeries(req EditSeriesRequest, _ *struct{}) error {
+	log := slog.With(slog.String("sid", req.SID), slog.String("field", req.Field), slog.Bool("remove", req.Remove))
+
+	log.Info("Editing series")
+	var err error
+	if req.Remove {
+		err = s.store.RemoveSeriesOverride(req.SID, req.Field)
+	} else {
+		err = s.store.SetSeriesOverride(req.SID, req.Field, req.Value)
+	}
+	if err != nil {
+		log.Error("Failed to edit series", slog.Any("err", err))
+		return err
+	}
+	log.Info("Edited series")
+	return nil
+}
+
+type EditEntryRequest struct {
+	SID, EID, Field, Value string
+	Remove                 bool
+}
+
+func (s *Server) EditEntry(req EditEntryRequest, _ *struct{}) error {
+	log := slog.With(slog.String("sid", req.SID), slog.String("eid", req.EID), slog.String("field", req.Field),
+		slog.Bool("remove", req.Remove))
+
+	log.Info("Editing entry")
+	var err error
+	if req.Remove {
+		err = s.store.RemoveEntryOverride(req.SID, req.EID, req.Field)
+	} else {
+		err = s.store.SetEntryOverride(req.SID, req.EID, req.Field, req.Value)
+	}
+	if err != nil {
+		log.Error("Failed to edit entry", slog.Any("err", err))
+		return err
+	}
+	log.Info("Edited entry")
+	return nil
+}
+
+type EditSeriesTagRequest struct {
+	SID, Tag string
+	Remove   bool
+}
+
+func (s *Server) EditSeriesTag(req EditSeriesTagRequest, _ *struct{}) error {
+	log := slog.With(slog.String("sid", req.SID), slog.String("tag", req.Tag), slog.Bool("remove", req.Remove))
+
+	log.Info("Editing series tag")
+	var err error
+	if req.Remove {
+		err = s.store.RemoveSeriesTag(req.SID, req.Tag)
+	} else {
+		err = s.store.AddSeriesTag(req.SID, req.Tag)
+	}
+	if err != nil {
+		log.Error("Failed to edit series tag", slog.Any("err", err))
+		return err
+	}
+	log.Info("Edited series tag")
+	return nil
+}
+
+type ResolveDuplicateRequest struct {
+	SID, EID, Prefer string
+}
+
+func (s *Server) ResolveDuplicate(req ResolveDuplicateRequest, _ *struct{}) error {
+	log := slog.With(slog.String("sid", req.SID), slog.String("eid", req.EID), slog.String("prefer", req.Prefer))
+
+	log.Info("Resolving duplicate")
+	if err := s.store.ResolveDuplicate(req.SID, req.EID, req.Prefer); err != nil {
+		log.Error("Failed to resolve duplicate", slog.Any("err", err))
+		return err
+	}
+	log.Info("Resolved duplicate")
+	return nil
+}
+
 // Helpers
 
 func sendFile(w http.ResponseWriter, f *bytes.Buffer, mime string) {
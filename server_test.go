@@ -1,13 +1,18 @@
 package tanuki
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -87,6 +92,8 @@ func TestServer_GetCatalog(t *testing.T) {
   <link href="/opds/v1.2/catalog" rel="start" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
   <link href="/opds/v1.2/catalog" rel="self" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
   <link href="/opds/v1.2/search" rel="search" type="application/opensearchdescription+xml"></link>
+  <link href="/opds/v1.2/tags" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
+  <link href="/opds/v1.2/creators" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
   <title>Catalog</title>
   <updated>0001-01-01T00:00:00Z</updated>
   <author>
@@ -109,6 +116,8 @@ func TestServer_GetCatalog(t *testing.T) {
   <link href="/opds/v1.2/catalog" rel="start" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
   <link href="/opds/v1.2/catalog" rel="self" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
   <link href="/opds/v1.2/search" rel="search" type="application/opensearchdescription+xml"></link>
+  <link href="/opds/v1.2/tags" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
+  <link href="/opds/v1.2/creators" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
   <title>Catalog</title>
   <updated>2022-08-11T16:53:23+01:00</updated>
   <author>
@@ -117,23 +126,41 @@ func TestServer_GetCatalog(t *testing.T) {
   </author>
   <entry>
     <title>20th Century Boys</title>
+    <author>
+      <name>Naoki Urusawa</name>
+      <uri>/opds/v1.2/creators/_cSzGmOky8yuW8daLG-r8VOfmEjLM35WLrdq0GaG0NQ</uri>
+    </author>
     <updated>2022-08-11T16:53:23+01:00</updated>
     <id>PvHfuhL24GD6jo-PKLbPj_KvRikLn2WjCw_gOaXKRyI</id>
     <content></content>
+    <link href="/opds/v1.2/series/PvHfuhL24GD6jo-PKLbPj_KvRikLn2WjCw_gOaXKRyI/cover?thumbnail=true" rel="http://opds-spec.org/image/thumbnail" type="image/jpeg"></link>
+    <link href="/opds/v1.2/series/PvHfuhL24GD6jo-PKLbPj_KvRikLn2WjCw_gOaXKRyI/cover" rel="http://opds-spec.org/image" type="image/jpeg"></link>
     <link href="/opds/v1.2/series/PvHfuhL24GD6jo-PKLbPj_KvRikLn2WjCw_gOaXKRyI" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
   </entry>
   <entry>
     <title>Akira</title>
+    <author>
+      <name>Katsuhiro Otomo</name>
+      <uri>/opds/v1.2/creators/_Y1wv0899OyE8AwdJfdD8g8iY3CaShU9I--ZPeBWjKc</uri>
+    </author>
     <updated>2022-08-11T16:53:23+01:00</updated>
     <id>rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c</id>
     <content></content>
+    <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/cover?thumbnail=true" rel="http://opds-spec.org/image/thumbnail" type="image/jpeg"></link>
+    <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/cover" rel="http://opds-spec.org/image" type="image/jpeg"></link>
     <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
   </entry>
   <entry>
     <title>Amano</title>
+    <author>
+      <name>Nekoguchi</name>
+      <uri>/opds/v1.2/creators/_3IHLiTZppn4z0h0pcQFOkMNFLS55yZVvTqr84DnhaQ</uri>
+    </author>
     <updated>2022-08-11T16:53:23+01:00</updated>
     <id>wNgocaIzfIjmFcxC-5I3S5pEpjRKjDY4nRxg9Ko-z7k</id>
     <content></content>
+    <link href="/opds/v1.2/series/wNgocaIzfIjmFcxC-5I3S5pEpjRKjDY4nRxg9Ko-z7k/cover?thumbnail=true" rel="http://opds-spec.org/image/thumbnail" type="image/jpeg"></link>
+    <link href="/opds/v1.2/series/wNgocaIzfIjmFcxC-5I3S5pEpjRKjDY4nRxg9Ko-z7k/cover" rel="http://opds-spec.org/image" type="image/jpeg"></link>
     <link href="/opds/v1.2/series/wNgocaIzfIjmFcxC-5I3S5pEpjRKjDY4nRxg9Ko-z7k" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
   </entry>
 </feed>`, string(rec.Body.Bytes()))
@@ -152,6 +179,8 @@ func TestServer_GetCatalog(t *testing.T) {
   <link href="/opds/v1.2/catalog" rel="start" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
   <link href="/opds/v1.2/catalog" rel="self" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
   <link href="/opds/v1.2/search" rel="search" type="application/opensearchdescription+xml"></link>
+  <link href="/opds/v1.2/tags" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
+  <link href="/opds/v1.2/creators" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
   <title>Catalog</title>
   <updated>2022-08-11T16:53:23+01:00</updated>
   <author>
@@ -160,15 +189,90 @@ func TestServer_GetCatalog(t *testing.T) {
   </author>
   <entry>
     <title>Akira</title>
+    <author>
+      <name>Katsuhiro Otomo</name>
+      <uri>/opds/v1.2/creators/_Y1wv0899OyE8AwdJfdD8g8iY3CaShU9I--ZPeBWjKc</uri>
+    </author>
     <updated>2022-08-11T16:53:23+01:00</updated>
     <id>rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c</id>
     <content></content>
+    <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/cover?thumbnail=true" rel="http://opds-spec.org/image/thumbnail" type="image/jpeg"></link>
+    <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/cover" rel="http://opds-spec.org/image" type="image/jpeg"></link>
     <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
   </entry>
 </feed>`, string(rec.Body.Bytes()))
 	})
 }
 
+func TestServer_GetTags(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+	lib, err := ParseLibrary("tests/lib-comicinfo", LibraryOptions{})
+	require.NoError(t, err)
+	require.NoError(t, s.PopulateCatalog(lib))
+	r := router(s)
+
+	t.Run("tags", func(t *testing.T) {
+		req := newServerHttpReq("/opds/v1.2/tags")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `<link href="/opds/v1.2/tags" rel="self" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>`)
+		require.Contains(t, rec.Body.String(), `<title>Romance</title>`)
+		require.Contains(t, rec.Body.String(), `<content>1 series</content>`)
+		require.Contains(t, rec.Body.String(), `<link href="/opds/v1.2/tags/I_fIyIdCmY4RcWJShL7hgrU--13I37TCkcdbxor8fC8" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>`)
+	})
+
+	t.Run("tagged series", func(t *testing.T) {
+		req := newServerHttpReq("/opds/v1.2/tags/I_fIyIdCmY4RcWJShL7hgrU--13I37TCkcdbxor8fC8")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `<title>Romance</title>`)
+		require.Contains(t, rec.Body.String(), `<link href="/opds/v1.2/series/wNgocaIzfIjmFcxC-5I3S5pEpjRKjDY4nRxg9Ko-z7k" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>`)
+	})
+
+	t.Run("missing tag", func(t *testing.T) {
+		req := newServerHttpReq("/opds/v1.2/tags/a")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestServer_GetCreators(t *testing.T) {
+	r, s := newPopulatedRouter(t)
+	defer mustCloseStore(t, s)
+
+	t.Run("creators", func(t *testing.T) {
+		req := newServerHttpReq("/opds/v1.2/creators")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `<link href="/opds/v1.2/creators" rel="self" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>`)
+		require.Contains(t, rec.Body.String(), `<title>Katsuhiro Otomo</title>`)
+		require.Contains(t, rec.Body.String(), `<content>writer - 1 series</content>`)
+		require.Contains(t, rec.Body.String(), `<link href="/opds/v1.2/creators/_Y1wv0899OyE8AwdJfdD8g8iY3CaShU9I--ZPeBWjKc" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>`)
+	})
+
+	t.Run("creator's series", func(t *testing.T) {
+		req := newServerHttpReq("/opds/v1.2/creators/_Y1wv0899OyE8AwdJfdD8g8iY3CaShU9I--ZPeBWjKc")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `<title>Katsuhiro Otomo</title>`)
+		require.Contains(t, rec.Body.String(), `<link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>`)
+		require.NotContains(t, rec.Body.String(), `<title>Amano</title>`)
+	})
+
+	t.Run("missing creator", func(t *testing.T) {
+		req := newServerHttpReq("/opds/v1.2/creators/a")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
 func TestServer_GetEntries(t *testing.T) {
 	r, s := newPopulatedRouter(t)
 	defer mustCloseStore(t, s)
@@ -201,7 +305,7 @@ func TestServer_GetEntries(t *testing.T) {
     <title>Volume 01</title>
     <updated>2022-08-11T16:53:23+01:00</updated>
     <id>1f2Xo_TQk-nS-9I9QsRm3zVNawdW6HlOUYJsV22wENk</id>
-    <content>zip - 26.3 KiB</content>
+    <content>Vol 1 - zip - 26.3 KiB</content>
     <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/entries/1f2Xo_TQk-nS-9I9QsRm3zVNawdW6HlOUYJsV22wENk/cover?thumbnail=true" rel="http://opds-spec.org/image/thumbnail" type="image/jpeg"></link>
     <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/entries/1f2Xo_TQk-nS-9I9QsRm3zVNawdW6HlOUYJsV22wENk/cover" rel="http://opds-spec.org/image" type="image/jpeg"></link>
     <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/entries/1f2Xo_TQk-nS-9I9QsRm3zVNawdW6HlOUYJsV22wENk/archive" rel="http://opds-spec.org/acquisition" type="application/zip"></link>
@@ -211,7 +315,7 @@ func TestServer_GetEntries(t *testing.T) {
     <title>Volume 02</title>
     <updated>2022-08-11T16:53:23+01:00</updated>
     <id>ntnxQLqcSL5bQDAnFaRJKCqLMTjPtdqCEQZ1vipuw_o</id>
-    <content>zip - 18.3 KiB</content>
+    <content>Vol 2 - zip - 18.3 KiB</content>
     <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/entries/ntnxQLqcSL5bQDAnFaRJKCqLMTjPtdqCEQZ1vipuw_o/cover?thumbnail=true" rel="http://opds-spec.org/image/thumbnail" type="image/jpeg"></link>
     <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/entries/ntnxQLqcSL5bQDAnFaRJKCqLMTjPtdqCEQZ1vipuw_o/cover" rel="http://opds-spec.org/image" type="image/jpeg"></link>
     <link href="/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/entries/ntnxQLqcSL5bQDAnFaRJKCqLMTjPtdqCEQZ1vipuw_o/archive" rel="http://opds-spec.org/acquisition" type="application/zip"></link>
@@ -221,6 +325,62 @@ func TestServer_GetEntries(t *testing.T) {
 	})
 }
 
+func TestServer_GetFolders(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+	lib, err := ParseLibrary("tests/lib-nested", LibraryOptions{})
+	require.NoError(t, err)
+	require.NoError(t, s.PopulateCatalog(lib))
+	r := router(s)
+
+	endpoint := "/opds/v1.2/series/" + akiraSeries.SID
+	seasonLink := fmt.Sprintf(`<link href="%s/folders/%s" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>`,
+		endpoint, folderID("Season 1"))
+	extrasLink := fmt.Sprintf(`<link href="%s/folders/%s" rel="subsection" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>`,
+		endpoint, folderID("Season 1/Extras"))
+
+	t.Run("series", func(t *testing.T) {
+		req := newServerHttpReq(endpoint)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `<title>All Entries</title>`)
+		require.Contains(t, rec.Body.String(), `<content>4 entries</content>`)
+		require.Contains(t, rec.Body.String(), seasonLink)
+		require.Contains(t, rec.Body.String(), `<title>Side Stories</title>`)
+		require.NotContains(t, rec.Body.String(), extrasLink)
+		require.Contains(t, rec.Body.String(), `<title>Volume 01</title>`)
+		require.NotContains(t, rec.Body.String(), `<title>Volume 02</title>`)
+	})
+
+	t.Run("folder", func(t *testing.T) {
+		req := newServerHttpReq(endpoint + "/folders/" + folderID("Season 1"))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `<title>Akira / Season 1</title>`)
+		require.Contains(t, rec.Body.String(), extrasLink)
+		require.Contains(t, rec.Body.String(), `<title>Volume 02</title>`)
+		require.NotContains(t, rec.Body.String(), `<title>Volume 01</title>`)
+	})
+
+	t.Run("all entries", func(t *testing.T) {
+		req := newServerHttpReq(endpoint + "/all")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, 4, strings.Count(rec.Body.String(), "<entry>"))
+		require.NotContains(t, rec.Body.String(), "/folders/")
+	})
+
+	t.Run("missing folder", func(t *testing.T) {
+		req := newServerHttpReq(endpoint + "/folders/a")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
 func TestServer_GetArchive(t *testing.T) {
 	r, s := newPopulatedRouter(t)
 	defer mustCloseStore(t, s)
@@ -248,6 +408,45 @@ func TestServer_GetArchive(t *testing.T) {
 	})
 }
 
+func TestServer_GetArchive_Folder(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+	r := router(s)
+
+	lib, err := ParseLibrary("tests/lib-dir", LibraryOptions{})
+	require.NoError(t, err)
+	require.NoError(t, s.PopulateCatalog(lib))
+
+	e, err := ParseEntry("tests/lib-dir/Amano/Vol.01 Ch.0002 - B")
+	require.NoError(t, err)
+	endpoint := fmt.Sprintf("/opds/v1.2/series/%s/entries/%s/archive", amanoSeries.SID, e.EID)
+
+	req := newServerHttpReq(endpoint)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Header().Get("Content-Disposition"), "Vol.01+Ch.0002+-+B.zip")
+
+	// The folder is zipped in page order
+	body := rec.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	require.Len(t, zr.File, len(e.Pages))
+	for i, f := range zr.File {
+		require.Equal(t, e.Pages[i].Path, f.Name)
+
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		expected, err := os.ReadFile(filepath.Join(e.Archive, f.Name))
+		require.NoError(t, err)
+		require.Equal(t, expected, data)
+	}
+}
+
 func TestServer_GetCover(t *testing.T) {
 	r, s := newPopulatedRouter(t)
 	defer mustCloseStore(t, s)
@@ -286,6 +485,43 @@ func TestServer_GetCover(t *testing.T) {
 	})
 }
 
+func TestServer_GetSeriesCover(t *testing.T) {
+	r, s := newPopulatedRouter(t)
+	defer mustCloseStore(t, s)
+
+	endpoint := fmt.Sprintf("/opds/v1.2/series/%s/cover", akiraSeries.SID)
+
+	t.Run("authorisation required", func(t *testing.T) {
+		req := httptest.NewRequest("GET", endpoint, nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("original", func(t *testing.T) {
+		req := newServerHttpReq(endpoint)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		buf, _, err := s.GetSeriesCover(akiraSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, buf.Bytes(), rec.Body.Bytes())
+	})
+
+	t.Run("thumbnail", func(t *testing.T) {
+		req := newServerHttpReq(endpoint + "?thumbnail=true")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+
+		buf, _, err := s.GetSeriesThumbnail(akiraSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, buf.Bytes(), rec.Body.Bytes())
+	})
+}
+
 func TestServer_GetPage(t *testing.T) {
 	r, s := newPopulatedRouter(t)
 	defer mustCloseStore(t, s)
@@ -318,6 +554,41 @@ func TestServer_GetPage(t *testing.T) {
 	})
 }
 
+func TestServer_GetPages(t *testing.T) {
+	r, s := newPopulatedRouter(t)
+	defer mustCloseStore(t, s)
+
+	endpoint := "/opds/v1.2/series/rxogaPHmjap2Gwpwuo5K3EO7JYgxU21JCRuZBOvdc2c/entries/ntnxQLqcSL5bQDAnFaRJKCqLMTjPtdqCEQZ1vipuw_o/pages"
+
+	t.Run("authorisation required", func(t *testing.T) {
+		req := httptest.NewRequest("GET", endpoint, nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("valid auth", func(t *testing.T) {
+		req := newServerHttpReq(endpoint)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var pages []pageInfo
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pages))
+		require.Len(t, pages, 10)
+		require.Equal(t, pageInfo{Number: 0, Mime: "image/jpeg", Width: 44, Height: 64, Bytes: 2422}, pages[0])
+		require.Equal(t, pageInfo{Number: 2, Mime: "image/jpeg", Width: 64, Height: 47, Bytes: 1208, Wide: true}, pages[2])
+	})
+
+	t.Run("missing entry", func(t *testing.T) {
+		req := newServerHttpReq("/opds/v1.2/series/a/entries/b/pages")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
 func TestServer_ScanLibrary(t *testing.T) {
 	conf := DefaultServerConfig()
 	conf.ScanInterval = duration{time.Second}
@@ -332,6 +603,49 @@ func TestServer_ScanLibrary(t *testing.T) {
 	}, 5*time.Second, time.Second)
 }
 
+func TestServer_WatchLibrary(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip(errWatchUnsupported)
+	}
+
+	library := t.TempDir()
+	require.NoError(t, os.CopyFS(library, os.DirFS("tests/lib")))
+	conf := DefaultServerConfig()
+	conf.Watch = true
+	conf.WatchDelay = duration{100 * time.Millisecond}
+	s := newTestServer(t, conf)
+	s.config.LibraryPath = library
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	count := func(sid string) int {
+		es, err := s.store.GetEntries(sid)
+		require.NoError(t, err)
+		return len(es)
+	}
+	require.Eventually(t, func() bool { return count(akiraSeries.SID) == 2 }, 5*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, os.Remove(filepath.Join(library, "Akira", "Volume 02.zip")))
+	require.Eventually(t, func() bool { return count(akiraSeries.SID) == 1 }, 5*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, os.RemoveAll(filepath.Join(library, "Amano")))
+	require.Eventually(t, func() bool {
+		ctl, err := s.store.GetCatalog()
+		require.NoError(t, err)
+		return len(ctl) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestDebounceDelay(t *testing.T) {
+	delay, maxDelay := 10*time.Second, time.Minute
+	require.Equal(t, delay, debounceDelay(delay, maxDelay, 0))
+	require.Equal(t, 5*time.Second, debounceDelay(delay, maxDelay, 55*time.Second))
+
+	// Changes which have waited too long are scanned straight away
+	require.Equal(t, time.Duration(0), debounceDelay(delay, maxDelay, 2*time.Minute))
+	require.Equal(t, delay, debounceDelay(delay, 0, 2*time.Minute))
+}
+
 // Utils
 
 func newTestServer(t *testing.T, conf ServerConfig) *Server {
@@ -351,7 +665,7 @@ func newServerHttpReq(target string) *http.Request {
 func newPopulatedRouter(t *testing.T) (*chi.Mux, *Store) {
 	s := mustOpenStoreMem(t)
 
-	lib, err := ParseLibrary("tests/lib")
+	lib, err := ParseLibrary("tests/lib", LibraryOptions{})
 	require.NoError(t, err)
 	require.NoError(t, s.PopulateCatalog(lib))
 
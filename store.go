@@ -1,8 +1,9 @@
 package tanuki
 
 import (
-	"archive/zip"
 	"bytes"
+	"database/sql"
+	"errors"
 	"fmt"
 	"image"
 	"image/jpeg"
@@ -10,6 +11,9 @@ import (
 	_ "image/png"
 	"io"
 	"log/slog"
+	"mime"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -29,12 +33,16 @@ import (
 
 const InMemory string = "file::memory:"
 
+// Decompressed blocks of solid archives are cached up to this size
+const blockCacheSize = 128 * 1024 * 1024
+
 type Store struct {
-	pool *sqlx.DB
+	pool  *sqlx.DB
+	cache *blockCache
 }
 
 func NewStore(path string) (*Store, error) {
-	pool, err := sqlx.Connect("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=foreign_keys(on)")
+	pool, err := sqlx.Connect("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=foreign_keys(on)&_time_format=sqlite")
 	if err != nil {
 		return nil, fmt.Errorf("connect to %s: %w", path, err)
 	}
@@ -54,57 +62,10 @@ func NewStore(path string) (*Store, error) {
 		return b.String()
 	})
 
-	s := &Store{pool: pool}
-
-	stmts := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			name TEXT PRIMARY KEY UNIQUE,
-			pass TEXT NOT NULL
-		);`,
-		`CREATE TABLE IF NOT EXISTS series (
-			sid       TEXT     PRIMARY KEY UNIQUE,
-			title     TEXT     NOT NULL    UNIQUE,
-			author    TEXT,
-			mod_time  DATETIME NOT NULL,
-			position  INTEGER  NOT NULL,
-		    missing   INTEGER  NOT NULL
-		);`,
-		`CREATE TABLE IF NOT EXISTS entries (
-			eid       TEXT     NOT NULL,
-			sid       TEXT     NOT NULL,
-			title     TEXT     NOT NULL,
-			mod_time  DATETIME NOT NULL,
-			archive   TEXT     NOT NULL,
-			pages     TEXT     NOT NULL,
-			filesize  INTEGER  NOT NULL,
-			position  INTEGER  NOT NULL,
-		    missing   INTEGER  NOT NULL,
-
-			-- Relationships
-			PRIMARY KEY (sid, eid),
-			FOREIGN KEY (sid) 
-		    	REFERENCES series (sid)
-                	ON UPDATE CASCADE 
-                	ON DELETE CASCADE 
-			);`,
-		`CREATE TABLE IF NOT EXISTS thumbnails (
-			eid       TEXT     NOT NULL,
-			sid       TEXT     NOT NULL,
-			mod_time  DATETIME NOT NULL,
-			data      BLOB     NOT NULL,
-
-			-- Relationships
-			PRIMARY KEY (sid, eid),
-			FOREIGN KEY (sid, eid)
-				REFERENCES entries (sid, eid)
-                	ON UPDATE CASCADE 
-                	ON DELETE CASCADE
-			);`,
-	}
-	for _, stmt := range stmts {
-		if _, err := s.pool.Exec(stmt); err != nil {
-			return nil, err
-		}
+	s := &Store{pool: pool, cache: newBlockCache(blockCacheSize)}
+
+	if err := s.migrate(); err != nil {
+		return nil, fmt.Errorf("migrate store: %w", err)
 	}
 
 	var exists bool
@@ -147,7 +108,7 @@ func (s *Store) Dump() (string, error) {
 
 		for _, name := range names {
 			// We don't want useless byte output
-			if name == "thumbnails" {
+			if name == "thumbnails" || name == "series_thumbnails" {
 				continue
 			}
 
@@ -271,39 +232,241 @@ func (s *Store) AuthLogin(name, pass string) bool {
 // Series
 
 func (s *Store) addSeries(tx *sqlx.Tx, sr Series, position int) error {
-	stmt := `INSERT INTO series (sid, title, author, mod_time, position, missing) 
-			 Values (?, ?, ?, ?, ?, 0)
+	stmt := `INSERT INTO series (sid, title, author, mod_time, info, cover, path, position, missing) 
+			 Values (?, ?, ?, ?, ?, ?, ?, ?, 0)
 			 ON CONFLICT (sid)
 			 DO UPDATE SET sid=excluded.sid, title=excluded.title, author=excluded.author,
-						   mod_time=excluded.mod_time, position=excluded.position, 
-                           missing=excluded.missing`
-	_, err := tx.Exec(stmt, sr.SID, sr.Title, sr.Author, sr.ModTime, position)
-	return err
+						   mod_time=excluded.mod_time, info=excluded.info, cover=excluded.cover, path=excluded.path,
+						   position=excluded.position, missing=excluded.missing`
+	_, err := tx.Exec(stmt, sr.SID, sr.Title, sr.Author, sr.ModTime, sr.Info, sr.Cover, sr.Path, position)
+	if err != nil {
+		return err
+	}
+	if err := s.setSeriesTags(tx, sr.SID, sr.Info.Genres()); err != nil {
+		return err
+	}
+	return s.setSeriesCredits(tx, sr)
 }
 
 func (s *Store) GetSeries(sid string) (Series, error) {
 	var v Series
-	return v, s.pool.Get(&v, `SELECT sid, title, author, mod_time FROM series 
-		     				  WHERE sid = ?`, sid)
+	err := s.pool.Get(&v, `SELECT sid, title, author, mod_time, info, cover, path FROM series 
+		     			   WHERE sid = ?`, sid)
+	if err != nil {
+		return Series{}, err
+	}
+
+	ovs, err := s.getOverrides(s.pool, sid)
+	if err != nil {
+		return Series{}, err
+	}
+	ovs[""].applySeries(&v)
+
+	tags, err := s.getSeriesTags(s.pool, sid)
+	if err != nil {
+		return Series{}, err
+	}
+	applyTags(&v, tags)
+	return v, nil
+}
+
+// Returns the series stored with the title, overrides aside
+func (s *Store) seriesByTitle(title string) (Series, error) {
+	var v Series
+	return v, s.pool.Get(&v, `SELECT sid, title, author, mod_time, info, cover, path FROM series
+						      WHERE title = ?`, title)
+}
+
+// Series without their own cover use
+// the cover of their first entry
+func (s *Store) firstEntry(sid string) (string, error) {
+	es, err := s.GetEntries(sid)
+	if err != nil {
+		return "", err
+	}
+	if len(es) == 0 {
+		return "", fmt.Errorf("series has no entries: %s", sid)
+	}
+	return es[0].EID, nil
+}
+
+func (s *Store) GetSeriesCover(sid string) (*bytes.Buffer, string, error) {
+	sr, err := s.GetSeries(sid)
+	if err != nil {
+		return nil, "", err
+	}
+	if sr.Cover == "" {
+		eid, err := s.firstEntry(sid)
+		if err != nil {
+			return nil, "", err
+		}
+		return s.GetCover(sid, eid)
+	}
+
+	data, err := os.ReadFile(sr.Cover)
+	if err != nil {
+		return nil, "", err
+	}
+	return bytes.NewBuffer(data), mime.TypeByExtension(filepath.Ext(sr.Cover)), nil
+}
+
+// Returns the type of each series' cover, keyed by their SID. Series
+// without their own cover use their first entry's, which is found the
+// same way as GetEntries orders them
+// Feeds link to every series' cover, so the type of each entry's
+// cover is stored rather than read from the entry's pages
+func (s *Store) GetSeriesCoverTypes() (map[string]string, error) {
+	var srs []Series
+	if err := s.pool.Select(&srs, `SELECT sid, cover FROM series WHERE missing=0`); err != nil {
+		return nil, err
+	}
+	var es []struct {
+		Entry
+		CoverType string `db:"cover_type"`
+	}
+	err := s.pool.Select(&es, `SELECT sid, eid, title, volume, chapter, cover_type FROM entries
+		                       ORDER BY position ASC, ROWID DESC`)
+	if err != nil {
+		return nil, err
+	}
+	// Only the overrides which change the order are needed
+	var ovs []override
+	err = s.pool.Select(&ovs, `SELECT sid, eid, title, sort_title FROM overrides
+		                      WHERE eid != '' AND (title IS NOT NULL OR sort_title IS NOT NULL)`)
+	if err != nil {
+		return nil, err
+	}
+	entryOvs := make(map[entryKey]override, len(ovs))
+	for _, o := range ovs {
+		entryOvs[entryKey{o.SID, o.EID}] = o
+	}
+
+	first := make(map[string]Entry)
+	coverTypes := make(map[entryKey]string, len(es))
+	for _, row := range es {
+		e := row.Entry
+		entryOvs[entryKey{e.SID, e.EID}].applyEntry(&e)
+		if f, found := first[e.SID]; !found || entryLess(e, f) {
+			first[e.SID] = e
+		}
+		coverTypes[entryKey{e.SID, e.EID}] = row.CoverType
+	}
+	types := make(map[string]string, len(srs))
+	for _, sr := range srs {
+		if sr.Cover != "" {
+			types[sr.SID] = mime.TypeByExtension(filepath.Ext(sr.Cover))
+		} else if e, found := first[sr.SID]; found && coverTypes[entryKey{e.SID, e.EID}] != "" {
+			types[sr.SID] = coverTypes[entryKey{e.SID, e.EID}]
+		}
+	}
+	return types, nil
+}
+
+// Stores the type of the entry's cover, taking its cover override
+// into account, the entry's pages must be set
+func setCoverType(tx *sqlx.Tx, e Entry) error {
+	var o override
+	err := tx.Get(&o, `SELECT sid, eid, cover_page FROM overrides WHERE sid = ? AND eid = ?`, e.SID, e.EID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	o.applyEntry(&e)
+
+	var coverType string
+	if e.CoverPage < len(e.Pages) {
+		coverType = e.Pages[e.CoverPage].Mime
+	}
+	_, err = tx.Exec(`UPDATE entries SET cover_type = ? WHERE sid = ? AND eid = ?`, coverType, e.SID, e.EID)
+	return err
+}
+
+func (s *Store) GetSeriesThumbnail(sid string) (*bytes.Buffer, string, error) {
+	sr, err := s.GetSeries(sid)
+	if err != nil {
+		return nil, "", err
+	}
+	if sr.Cover == "" {
+		eid, err := s.firstEntry(sid)
+		if err != nil {
+			return nil, "", err
+		}
+		return s.GetThumbnail(sid, eid)
+	}
+
+	stat, err := os.Stat(sr.Cover)
+	if err != nil {
+		return nil, "", err
+	}
+	modTime := stat.ModTime().Round(0)
+
+	buf := bytes.NewBuffer(nil)
+	return buf, "image/jpeg", s.tx(func(tx *sqlx.Tx) error {
+		var thumb struct {
+			ModTime time.Time
+			Data    []byte
+		}
+		err := tx.Get(&thumb, `SELECT mod_time, data FROM series_thumbnails WHERE sid = ?`, sid)
+		if err == nil && thumb.ModTime.Equal(modTime) {
+			buf.Write(thumb.Data)
+			return nil
+		} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		// The thumbnail is either missing or outdated
+		f, err := os.Open(sr.Cover)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := encodeThumbnail(buf, f); err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO series_thumbnails (sid, mod_time, data) 
+			 	 Values (?, ?, ?)
+			 	 ON CONFLICT (sid)
+				 DO UPDATE SET mod_time=excluded.mod_time, data=excluded.data`
+		_, err = tx.Exec(stmt, sid, modTime, buf.Bytes())
+		return err
+	})
 }
 
 // Entries
 
 func (s *Store) addEntry(tx *sqlx.Tx, e Entry, position int) error {
-	stmt := `INSERT INTO entries (eid, sid, title, archive, pages, mod_time, filesize, position, missing) 
-			 Values (?, ?, ?, ?, ?, ?, ?, ?, 0)
+	stmt := `INSERT INTO entries (eid, sid, title, author, archive, pages, mod_time, filesize, info, volume, chapter, cover_page, fingerprint, folder, position, parse_version, missing) 
+			 Values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0)
 			 ON CONFLICT (eid, sid)
-			 DO UPDATE SET eid=excluded.eid, sid=excluded.sid, title=excluded.title, archive=excluded.archive,
+			 DO UPDATE SET eid=excluded.eid, sid=excluded.sid, title=excluded.title, author=excluded.author, archive=excluded.archive,
 				           pages=excluded.pages, mod_time=excluded.mod_time, filesize=excluded.filesize,
-						   position=excluded.position, missing=excluded.missing`
-	_, err := tx.Exec(stmt, e.EID, e.SID, e.Title, e.Archive, e.Pages, e.ModTime, e.Filesize, position)
-	return err
+						   info=excluded.info, volume=excluded.volume, chapter=excluded.chapter, cover_page=excluded.cover_page, 
+						   fingerprint=excluded.fingerprint, folder=excluded.folder, position=excluded.position, 
+						   parse_version=excluded.parse_version, missing=excluded.missing`
+	_, err := tx.Exec(stmt, e.EID, e.SID, e.Title, e.Author, e.Archive, e.Pages, e.ModTime, e.Filesize, e.Info, e.Volume, e.Chapter, e.CoverPage, e.Fingerprint, e.Folder, position, parseVersion)
+	if err != nil {
+		return err
+	}
+	if err := setCoverType(tx, e); err != nil {
+		return err
+	}
+	return s.setEntryCredits(tx, e)
 }
 
 func (s *Store) getEntry(tx *sqlx.Tx, sid, eid string) (Entry, error) {
 	var e Entry
-	return e, tx.Get(&e, `SELECT eid, sid, title, mod_time, archive, filesize, pages
-                          FROM entries WHERE sid = ? AND eid = ?`, sid, eid)
+	err := tx.Get(&e, `SELECT eid, sid, title, author, mod_time, archive, filesize, pages, info, volume, chapter, cover_page, fingerprint, folder
+                       FROM entries WHERE sid = ? AND eid = ?`, sid, eid)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	ovs, err := s.getOverrides(tx, sid)
+	if err != nil {
+		return Entry{}, err
+	}
+	ovs[eid].applyEntry(&e)
+	return e, nil
 }
 
 func (s *Store) GetEntry(sid, eid string) (Entry, error) {
@@ -316,19 +479,38 @@ func (s *Store) GetEntry(sid, eid string) (Entry, error) {
 }
 
 func (s *Store) GetEntries(sid string) ([]Entry, error) {
-	stmt := `SELECT sid, eid, title, mod_time, archive, filesize, pages FROM entries
+	stmt := `SELECT sid, eid, title, author, mod_time, archive, filesize, pages, info, volume, chapter, cover_page, fingerprint, folder FROM entries
 			 WHERE sid = ? ORDER BY position ASC, ROWID DESC `
 
 	var es []Entry
-	return es, s.pool.Select(&es, stmt, sid)
+	if err := s.pool.Select(&es, stmt, sid); err != nil {
+		return nil, err
+	}
+	ovs, err := s.getOverrides(s.pool, sid)
+	if err != nil {
+		return nil, err
+	}
+	for i := range es {
+		ovs[es[i].EID].applyEntry(&es[i])
+	}
+
+	// Entries are sorted by their numbers, which isn't
+	// possible in SQL since their titles are naturally
+	// sorted if they aren't numbered
+	sort.SliceStable(es, func(i, j int) bool {
+		return entryLess(es[i], es[j])
+	})
+	return es, nil
 }
 
 func (s *Store) getPage(tx *sqlx.Tx, sid, eid string, pageNum int) (*bytes.Buffer, string, error) {
 	var archive string
 	var ps Pages
+	var filesize int64
+	var modTime time.Time
 
-	row := tx.QueryRow("SELECT archive, pages FROM entries WHERE sid = ? AND eid = ?", sid, eid)
-	if err := row.Scan(&archive, &ps); err != nil {
+	row := tx.QueryRow("SELECT archive, pages, filesize, mod_time FROM entries WHERE sid = ? AND eid = ?", sid, eid)
+	if err := row.Scan(&archive, &ps, &filesize, &modTime); err != nil {
 		return nil, "", err
 	}
 
@@ -338,41 +520,25 @@ func (s *Store) getPage(tx *sqlx.Tx, sid, eid string, pageNum int) (*bytes.Buffe
 	}
 	p := ps[pageNum]
 
-	r, err := zip.OpenReader(archive)
-	if err != nil {
-		return nil, "", err
-	}
-	defer r.Close()
-
-	// If the path was originally non-UTF-8 encoded then we
-	// can't directly Open the path, since it doesn't exist
-	// under the UTF-8 name. Instead we need to do a page
-	// by page comparison in the ZIP file... blegh!
-	var f io.ReadCloser
-	if !p.NonUtf8 {
-		f, err = r.Open(p.Path)
-	} else {
-		for _, f2 := range r.File {
-			if !f2.NonUTF8 {
-				continue
-			}
-			f2Name, err := decodeCP437(f2.Name)
-			if err != nil {
-				return nil, "", err
-			}
-			if p.Path == f2Name {
-				f, err = f2.Open()
-				goto FoundFile
-			}
+	// Offsets are only valid for the archive which was scanned,
+	// if it's changed since then the page is found by its name
+	if p.Size > 0 {
+		stat, err := os.Stat(archive)
+		if err != nil {
+			return nil, "", err
+		}
+		if stat.Size() != filesize || !stat.ModTime().Equal(modTime) {
+			p.Offset, p.Size = 0, 0
 		}
-		return nil, "", fmt.Errorf("non-utf-8 page not found")
 	}
-FoundFile:
+
+	a, err := openArchive(archive, s.cache)
 	if err != nil {
 		return nil, "", err
 	}
+	defer a.Close()
 
-	content, err := io.ReadAll(f)
+	content, err := a.read(p)
 	if err != nil {
 		return nil, "", err
 	}
@@ -391,6 +557,29 @@ func (s *Store) GetPage(sid, eid string, pageNum int) (*bytes.Buffer, string, er
 	})
 }
 
+func (s *Store) GetCover(sid, eid string) (*bytes.Buffer, string, error) {
+	var buf *bytes.Buffer
+	var mime string
+	err := s.tx(func(tx *sqlx.Tx) error {
+		e, err := s.getEntry(tx, sid, eid)
+		if err != nil {
+			return err
+		}
+		buf, mime, err = s.getPage(tx, sid, eid, e.CoverPage)
+		return err
+	})
+	return buf, mime, err
+}
+
+func encodeThumbnail(w io.Writer, r io.Reader) error {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return err
+	}
+	thumb := resize.Thumbnail(300, 300, img, resize.Bicubic)
+	return jpeg.Encode(w, thumb, &jpeg.Options{Quality: 70})
+}
+
 func (s *Store) GetThumbnail(sid, eid string) (*bytes.Buffer, string, error) {
 	buf := bytes.NewBuffer(nil)
 	return buf, "image/jpeg", s.tx(func(tx *sqlx.Tx) error {
@@ -399,91 +588,349 @@ func (s *Store) GetThumbnail(sid, eid string) (*bytes.Buffer, string, error) {
 			return err
 		}
 
-		var thumbModTime time.Time
-		err = tx.Get(&thumbModTime, `SELECT mod_time FROM thumbnails WHERE sid = ? AND eid = ?`, sid, eid)
-		if err == nil && e.ModTime.Equal(thumbModTime) {
-			var data []byte
-			err = tx.Get(&data, `SELECT data FROM thumbnails WHERE sid = ? AND eid = ?`, sid, eid)
-			buf.Write(data)
+		// Thumbnails are outdated if the entry has been
+		// modified or its cover has been changed
+		var thumb struct {
+			ModTime time.Time
+			Page    int
+			Data    []byte
+		}
+		err = tx.Get(&thumb, `SELECT mod_time, page, data FROM thumbnails WHERE sid = ? AND eid = ?`, sid, eid)
+		if err == nil && e.ModTime.Equal(thumb.ModTime) && e.CoverPage == thumb.Page {
+			buf.Write(thumb.Data)
+			return nil
+		} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			return err
 		}
 
-		a := !thumbModTime.Equal(time.Time{}) && !thumbModTime.Equal(e.ModTime)
-		b := err != nil && strings.Contains(err.Error(), "no rows in result set")
-		if a || b {
-			p, _, err := s.getPage(tx, sid, eid, 0)
-			if err != nil {
-				return err
+		p, _, err := s.getPage(tx, sid, eid, e.CoverPage)
+		if err != nil {
+			return err
+		}
+		if err := encodeThumbnail(buf, p); err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO thumbnails (sid, eid, mod_time, page, data) 
+			 	 Values (?, ?, ?, ?, ?)
+			 	 ON CONFLICT (sid, eid)
+				 DO UPDATE SET sid=excluded.sid, eid=excluded.eid, mod_time=excluded.mod_time, 
+				               page=excluded.page, data=excluded.data`
+		_, err = tx.Exec(stmt, e.SID, e.EID, e.ModTime, e.CoverPage, buf.Bytes())
+		return err
+	})
+}
+
+// Catalog
+
+// Counts of the entries which were changed by populating the catalog
+type CatalogChanges struct {
+	Added     int
+	Changed   int
+	Removed   int
+	Unchanged int
+}
+
+func (c CatalogChanges) attr() slog.Attr {
+	return slog.Group("entries", slog.Int("added", c.Added), slog.Int("changed", c.Changed),
+		slog.Int("removed", c.Removed), slog.Int("unchanged", c.Unchanged))
+}
+
+func (s *Store) PopulateCatalog(input map[Series][]Entry) error {
+	_, err := s.UpdateCatalog(input)
+	return err
+}
+
+// Populates the catalog, only the entries which have changed
+// are rewritten
+func (s *Store) UpdateCatalog(input map[Series][]Entry) (CatalogChanges, error) {
+	var c CatalogChanges
+	return c, s.tx(func(tx *sqlx.Tx) error {
+		var err error
+		c, err = s.updateCatalog(tx, input, nil)
+		return err
+	})
+}
+
+// Updates the series stored at the paths without touching the
+// rest of the catalog, series which were stored at the paths but
+// aren't in the input are removed, e.g. if their folder was deleted
+func (s *Store) UpdateSeries(paths []string, input map[Series][]Entry) (CatalogChanges, error) {
+	var c CatalogChanges
+	return c, s.tx(func(tx *sqlx.Tx) error {
+		scope := make(map[string]struct{})
+		for _, p := range paths {
+			// Series whose path isn't known would all be in scope
+			if p == "" {
+				return fmt.Errorf("series path is empty")
 			}
-			img, _, err := image.Decode(p)
-			if err != nil {
+			var sids []string
+			if err := tx.Select(&sids, `SELECT sid FROM series WHERE path = ?`, p); err != nil {
 				return err
 			}
-			thumb := resize.Thumbnail(300, 300, img, resize.Bicubic)
-			err = jpeg.Encode(buf, thumb, &jpeg.Options{Quality: 70})
-			if err != nil {
-				return err
+			for _, sid := range sids {
+				scope[sid] = struct{}{}
 			}
-
-			stmt := `INSERT INTO thumbnails (sid, eid, mod_time, data) 
-			 		 Values (?, ?, ?, ?)
-			 	     ON CONFLICT (sid, eid)
-					 DO UPDATE SET sid=excluded.sid, eid=excluded.eid, 
-				     	           mod_time=excluded.mod_time, data=excluded.data`
-			_, err = tx.Exec(stmt, e.SID, e.EID, e.ModTime, buf.Bytes())
-			return err
+		}
+		for sr := range input {
+			scope[sr.SID] = struct{}{}
 		}
 
+		var err error
+		c, err = s.updateCatalog(tx, input, scope)
 		return err
 	})
 }
 
-// Catalog
+// Only the series in the scope, and their entries, are updated,
+// if the scope's nil then the whole catalog is
+func (s *Store) updateCatalog(tx *sqlx.Tx, input map[Series][]Entry, scope map[string]struct{}) (CatalogChanges, error) {
+	var c CatalogChanges
 
-func (s *Store) PopulateCatalog(input map[Series][]Entry) error {
-	return s.tx(func(tx *sqlx.Tx) error {
+	states, err := s.getSeriesStates(tx)
+	if err != nil {
+		return c, err
+	}
+	var maxPosition int
+	for _, st := range states {
+		maxPosition = max(maxPosition, st.Position)
+	}
+	if scope == nil {
 		_, err := tx.Exec(`UPDATE series SET missing=1`)
 		if err != nil {
-			return err
+			return c, err
 		}
-		_, err = tx.Exec(`UPDATE entries SET missing=1`)
-		if err != nil {
-			return err
+	} else {
+		for sid := range states {
+			if !inScope(scope, sid) {
+				continue
+			}
+			_, err := tx.Exec(`UPDATE series SET missing=1 WHERE sid = ?`, sid)
+			if err != nil {
+				return c, err
+			}
 		}
+	}
+	stored, err := s.getEntryStates(tx, scope)
+	if err != nil {
+		return c, err
+	}
 
-		// We need to sort our series input
-		// since maps don't iterate in sorted
-		// order
-		ordered := make([]Series, 0)
-		for series := range input {
-			ordered = append(ordered, series)
-		}
-		sort.SliceStable(ordered, func(i, j int) bool {
-			return natural.Less(ordered[i].Title, ordered[j].Title)
-		})
+	// We need to sort our series input
+	// since maps don't iterate in sorted
+	// order
+	ordered := make([]Series, 0)
+	for series := range input {
+		ordered = append(ordered, series)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return natural.Less(ordered[i].Title, ordered[j].Title)
+	})
 
-		for i, series := range ordered {
-			if err := s.addSeries(tx, series, i+1); err != nil {
-				return err
+	// Renamed entries keep their original EID
+	entries, moved, err := s.resolveEntries(tx, ordered, input, scope)
+	if err != nil {
+		return c, err
+	}
+
+	kept := make(map[entryKey]struct{})
+	for i, sr := range ordered {
+		// Series outside of the scope keep their position,
+		// the catalog's sorted by title when it's read anyway
+		st, found := states[sr.SID]
+		position := i + 1
+		if scope != nil {
+			if found {
+				position = st.Position
+			} else {
+				maxPosition++
+				position = maxPosition
 			}
-			for j, entry := range input[series] {
-				if err := s.addEntry(tx, entry, j+1); err != nil {
-					return err
+		}
+		// Series are only rewritten, along with their tags and
+		// credits, if they or their entries have changed
+		if found && st.unchanged(sr) && !entriesChanged(entries[i], stored, moved) {
+			_, err := tx.Exec(`UPDATE series SET position = ?, missing = 0 WHERE sid = ?`, position, sr.SID)
+			if err != nil {
+				return c, err
+			}
+		} else {
+			// A series which is being removed, or is yet to be
+			// updated, may still have the title, so it's freed
+			_, err := tx.Exec(`UPDATE series SET title = sid WHERE title = ? AND sid != ? AND missing = 1`,
+				sr.Title, sr.SID)
+			if err != nil {
+				return c, err
+			}
+			if err := s.addSeries(tx, sr, position); err != nil {
+				return c, err
+			}
+		}
+		for j, entry := range entries[i] {
+			k := entryKey{entry.SID, entry.EID}
+			kept[k] = struct{}{}
+			if from, found := moved[k]; found {
+				if err := s.moveEntry(tx, from, entry.SID, entry.EID); err != nil {
+					return c, err
 				}
+				kept[entryKey{from, entry.EID}] = struct{}{}
+				c.Changed++
+			} else if st, found := stored[k]; !found {
+				c.Added++
+			} else if st.unchanged(entry) {
+				c.Unchanged++
+				if st.Position != j+1 {
+					_, err := tx.Exec(`UPDATE entries SET position = ? WHERE sid = ? AND eid = ?`, j+1, entry.SID, entry.EID)
+					if err != nil {
+						return c, err
+					}
+				}
+				continue
+			} else {
+				c.Changed++
+			}
+			if err := s.addEntry(tx, entry, j+1); err != nil {
+				return c, err
 			}
 		}
+	}
 
-		_, err = tx.Exec(`DELETE FROM series WHERE missing=1`)
+	for k := range stored {
+		if _, found := kept[k]; found {
+			continue
+		}
+		_, err := tx.Exec(`DELETE FROM entries WHERE sid = ? AND eid = ?`, k.SID, k.EID)
 		if err != nil {
-			return err
+			return c, err
 		}
-		_, err = tx.Exec(`DELETE FROM entries WHERE missing=1`)
-		return err
-	})
+		c.Removed++
+	}
+	_, err = tx.Exec(`DELETE FROM series WHERE missing=1`)
+	if err != nil {
+		return c, err
+	}
+	if err := s.deleteUnusedTags(tx); err != nil {
+		return c, err
+	}
+	return c, s.deleteUnusedCreators(tx)
+}
+
+func inScope(scope map[string]struct{}, sid string) bool {
+	if scope == nil {
+		return true
+	}
+	_, found := scope[sid]
+	return found
+}
+
+// What's recorded about a stored series to tell if it's changed
+type seriesState struct {
+	SID      string
+	Title    string
+	Author   string
+	ModTime  time.Time
+	Info     SeriesInfo
+	Cover    string
+	Path     string
+	Position int
+}
+
+func (s *Store) getSeriesStates(tx *sqlx.Tx) (map[string]seriesState, error) {
+	var v []seriesState
+	err := tx.Select(&v, `SELECT sid, title, author, mod_time, info, cover, path, position FROM series`)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]seriesState, len(v))
+	for _, st := range v {
+		m[st.SID] = st
+	}
+	return m, nil
+}
+
+func (st seriesState) unchanged(sr Series) bool {
+	return st.Title == sr.Title && st.Author == sr.Author && st.ModTime.Equal(sr.ModTime) &&
+		st.Info == sr.Info && st.Cover == sr.Cover && st.Path == sr.Path
+}
+
+// Whether any of a series' entries are new, moved or changed
+func entriesChanged(entries []Entry, stored map[entryKey]entryState, moved map[entryKey]string) bool {
+	for _, e := range entries {
+		k := entryKey{e.SID, e.EID}
+		if _, found := moved[k]; found {
+			return true
+		}
+		if st, found := stored[k]; !found || !st.unchanged(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// What's recorded about a stored entry to tell if it's changed
+type entryState struct {
+	SID          string
+	EID          string
+	Title        string
+	Archive      string
+	Filesize     int64
+	ModTime      time.Time
+	Fingerprint  string
+	Folder       string
+	Position     int
+	ParseVersion int
+}
+
+func (s *Store) getEntryStates(tx *sqlx.Tx, scope map[string]struct{}) (map[entryKey]entryState, error) {
+	var v []entryState
+	err := tx.Select(&v, `SELECT sid, eid, title, archive, filesize, mod_time, fingerprint, folder, position, parse_version FROM entries`)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[entryKey]entryState, len(v))
+	for _, st := range v {
+		if inScope(scope, st.SID) {
+			m[entryKey{st.SID, st.EID}] = st
+		}
+	}
+	return m, nil
+}
+
+func (st entryState) unchanged(e Entry) bool {
+	return st.ParseVersion == parseVersion && st.Title == e.Title && st.Archive == e.Archive &&
+		st.Filesize == e.Filesize && st.ModTime.Equal(e.ModTime) && st.Fingerprint == e.Fingerprint &&
+		st.Folder == e.Folder
+}
+
+// Returns the stored entries keyed by their archive, without their
+// overrides, so scans can reuse the ones which haven't changed. If
+// paths are given, only the entries of the series stored at them are
+func (s *Store) KnownEntries(paths ...string) (map[string]Entry, error) {
+	stmt := `SELECT eid, sid, title, author, mod_time, archive, filesize, pages, info, volume, chapter, cover_page, fingerprint, folder
+             FROM entries WHERE parse_version = ?`
+	args := []any{parseVersion}
+	if len(paths) > 0 {
+		q, qArgs, err := sqlx.In(` AND sid IN (SELECT sid FROM series WHERE path IN (?))`, paths)
+		if err != nil {
+			return nil, err
+		}
+		stmt += q
+		args = append(args, qArgs...)
+	}
+
+	var v []Entry
+	if err := s.pool.Select(&v, stmt, args...); err != nil {
+		return nil, err
+	}
+	m := make(map[string]Entry, len(v))
+	for _, e := range v {
+		m[e.Archive] = e
+	}
+	return m, nil
 }
 
 func (s *Store) GetCatalog() ([]Series, error) {
-	stmt := `SELECT sid, title, author, mod_time FROM series 
+	stmt := `SELECT sid, title, author, mod_time, info, cover, path FROM series 
 		     WHERE missing=0 ORDER BY position ASC, ROWID DESC`
 
 	var v []Series
@@ -491,5 +938,25 @@ func (s *Store) GetCatalog() ([]Series, error) {
 		return nil, err
 	}
 
+	// The catalog's sorted by the series' titles, which overrides
+	// can change, so it's sorted once they're applied. Series whose
+	// sort titles are the same, e.g. because of their overrides,
+	// stay in the order of their positions
+	ovs, err := s.getAllSeriesOverrides(s.pool)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.getAllSeriesTags(s.pool)
+	if err != nil {
+		return nil, err
+	}
+	for i := range v {
+		ovs[v[i].SID].applySeries(&v[i])
+		applyTags(&v[i], tags[v[i].SID])
+	}
+	sort.SliceStable(v, func(i, j int) bool {
+		return natural.Less(sortTitle(v[i].Title, v[i].SortTitle), sortTitle(v[j].Title, v[j].SortTitle))
+	})
+
 	return v, nil
 }
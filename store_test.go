@@ -1,13 +1,16 @@
 package tanuki
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"errors"
 	"image"
 	"image/jpeg"
 	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -235,6 +238,8 @@ func TestStore_AddEntry(t *testing.T) {
 			ModTime:  time.Now().Round(0), // Strip the monotonic clock reading
 			Pages:    Pages{{Path: "y", Mime: "z"}},
 			Filesize: 2000,
+			Author:   "v",
+			Info:     ComicInfo{Series: "u", Volume: 1, Summary: "t", Manga: "Yes"},
 		}
 		require.NoError(t, s.AddEntry(e, 2))
 
@@ -314,14 +319,44 @@ func TestStore_GetEntries(t *testing.T) {
 	}
 
 	// Add them in reverse order, but they should
-	// still be returned in their sorted order
+	// still be returned sorted by their title
 	require.NoError(t, s.AddEntry(es[2], 3))
 	require.NoError(t, s.AddEntry(es[1], 2))
 	require.NoError(t, s.AddEntry(es[0], 1))
 
 	ees, err := s.GetEntries("b")
 	require.NoError(t, err)
-	require.Equal(t, es, ees)
+	require.Equal(t, []Entry{es[0], es[2], es[1]}, ees)
+
+	t.Run("numbered entries", func(t *testing.T) {
+		s := mustOpenStoreMem(t)
+		defer mustCloseStore(t, s)
+		require.NoError(t, s.AddSeries(Series{SID: "b"}, 1))
+
+		titles := []string{
+			"Vol. 2",
+			"v2 ch. 3.5",
+			"Vol. 2 Ch. 10",
+			"Vol. 10",
+			"Ch. 30-31",
+			"Ch. 32",
+			"Extra 2",
+			"Extra 10",
+		}
+		for i := len(titles) - 1; i >= 0; i-- {
+			e := Entry{EID: titles[i], SID: "b", Title: titles[i], Pages: Pages{}}
+			e.Volume, e.Chapter = parseNumbers(titles[i])
+			require.NoError(t, s.AddEntry(e, len(titles)-i))
+		}
+
+		es, err := s.GetEntries("b")
+		require.NoError(t, err)
+		require.Len(t, es, len(titles))
+		for i, e := range es {
+			require.Equal(t, titles[i], e.Title)
+		}
+		require.Equal(t, Span{30, 31, true}, es[4].Chapter)
+	})
 }
 
 func TestStore_GetPage(t *testing.T) {
@@ -367,6 +402,167 @@ func TestStore_GetPage(t *testing.T) {
 	})
 }
 
+func TestStore_GetPage_ChangedArchive(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	path := filepath.Join(t.TempDir(), "a.cbt")
+	data, err := os.ReadFile("tests/lib-cbt/Amano/Amano Megumi wa Suki Darake! v01.cbt")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	e, err := ParseEntry(path)
+	require.NoError(t, err)
+	e.SID = "a"
+	require.NoError(t, s.AddSeries(Series{SID: e.SID, Title: e.SID}, 1))
+	require.NoError(t, s.AddEntry(e, 1))
+	expected, _, err := s.GetPage(e.SID, e.EID, 0)
+	require.NoError(t, err)
+
+	// Rewriting the archive with a file before
+	// the pages moves every page's offset
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0o644, Size: 1}))
+	_, err = tw.Write([]byte("a"))
+	require.NoError(t, err)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, tw.WriteHeader(h))
+		_, err = io.Copy(tw, tr)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	page, _, err := s.GetPage(e.SID, e.EID, 0)
+	require.NoError(t, err)
+	require.Equal(t, expected, page)
+}
+
+func TestStore_GetPage_Formats(t *testing.T) {
+	// Each archive contains the same pages as the
+	// ZIP archive, so they should all be identical
+	paths := []string{
+		"tests/lib-cbr/Amano/Amano Megumi wa Suki Darake! v01.cbr",
+		"tests/lib-cb7/Amano/Amano Megumi wa Suki Darake! v01.cb7",
+		"tests/lib-cbt/Amano/Amano Megumi wa Suki Darake! v01.cbt",
+		"tests/lib-tgz/Amano/Amano Megumi wa Suki Darake! v01.tar.gz",
+		"tests/lib-epub/Amano/Amano Megumi wa Suki Darake! v01.epub",
+	}
+
+	for _, path := range paths {
+		t.Run(archiveExt(path), func(t *testing.T) {
+			s := mustOpenStoreMem(t)
+			defer mustCloseStore(t, s)
+
+			zipEntry, err := ParseEntry("tests/lib/Amano/Amano Megumi wa Suki Darake! v01.zip")
+			require.NoError(t, err)
+			e, err := ParseEntry(path)
+			require.NoError(t, err)
+
+			// Both entries have the same SID and EID, so we
+			// store the entries under different series
+			zipEntry.SID = "zip"
+			e.SID = "other"
+			require.NoError(t, s.AddSeries(Series{SID: zipEntry.SID, Title: zipEntry.SID}, 1))
+			require.NoError(t, s.AddSeries(Series{SID: e.SID, Title: e.SID}, 2))
+			require.NoError(t, s.AddEntry(zipEntry, 1))
+			require.NoError(t, s.AddEntry(e, 1))
+
+			// We read the pages twice so that cached
+			// data is also compared
+			for range 2 {
+				for i, p := range e.Pages {
+					expected, _, err := s.GetPage(zipEntry.SID, zipEntry.EID, i)
+					require.NoError(t, err)
+					data, mime, err := s.GetPage(e.SID, e.EID, i)
+					require.NoError(t, err)
+					require.Equal(t, expected, data)
+					require.Equal(t, p.Mime, mime)
+				}
+			}
+
+			expected, _, err := s.GetThumbnail(zipEntry.SID, zipEntry.EID)
+			require.NoError(t, err)
+			thumb, _, err := s.GetThumbnail(e.SID, e.EID)
+			require.NoError(t, err)
+			require.Equal(t, expected, thumb)
+		})
+	}
+}
+
+func TestStore_GetPage_Folder(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	_, es, err := ParseSeries("tests/lib-dir/Amano")
+	require.NoError(t, err)
+	require.NoError(t, s.AddSeries(Series{SID: es[0].SID, Title: "Amano"}, 1))
+
+	// The folders contain the same images as the ZIP archive
+	r, err := zip.OpenReader(amanoEntries[0].Archive)
+	require.NoError(t, err)
+	defer r.Close()
+
+	i := 0
+	for pos, e := range es {
+		require.NoError(t, s.AddEntry(e, pos+1))
+		for j, p := range e.Pages {
+			f, err := r.Open(amanoEntries[0].Pages[i].Path)
+			require.NoError(t, err)
+			expected, err := io.ReadAll(f)
+			require.NoError(t, err)
+			i++
+
+			data, mime, err := s.GetPage(e.SID, e.EID, j)
+			require.NoError(t, err)
+			require.Equal(t, expected, data.Bytes())
+			require.Equal(t, p.Mime, mime)
+		}
+	}
+}
+
+func TestStore_GetPage_Pdf(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	// The PDFs embed the same images as the ZIP archives
+	for i, path := range []string{
+		"tests/lib-pdf/Akira/Volume 01.pdf",
+		"tests/lib-pdf/Akira/Volume 02.pdf",
+	} {
+		e, err := ParseEntry(path)
+		require.NoError(t, err)
+		e.SID = "pdf"
+		require.NoError(t, s.AddSeries(Series{SID: e.SID, Title: e.SID}, 1))
+		require.NoError(t, s.AddEntry(e, i+1))
+
+		r, err := zip.OpenReader(akiraEntries[i].Archive)
+		require.NoError(t, err)
+		defer r.Close()
+
+		for j := range e.Pages {
+			f, err := r.Open(akiraEntries[i].Pages[j].Path)
+			require.NoError(t, err)
+			expected, err := io.ReadAll(f)
+			require.NoError(t, err)
+
+			data, mime, err := s.GetPage(e.SID, e.EID, j)
+			require.NoError(t, err)
+			require.Equal(t, expected, data.Bytes())
+			require.Equal(t, "image/jpeg", mime)
+		}
+
+		_, _, err = s.GetThumbnail(e.SID, e.EID)
+		require.NoError(t, err)
+	}
+}
+
 func TestStore_GetThumbnail(t *testing.T) {
 	s := mustOpenStoreMem(t)
 	defer mustCloseStore(t, s)
@@ -431,6 +627,22 @@ func TestStore_GetThumbnail(t *testing.T) {
 		require.Equal(t, newThumb, buf)
 	})
 
+	t.Run("new thumbnail on changed cover page", func(t *testing.T) {
+		// The mod time is unchanged but a rescan chose
+		// a different cover page, e.g. after the archive's
+		// ComicInfo.xml was edited
+		orig, err := ParseEntry(path)
+		require.NoError(t, err)
+		e.Pages = append(Pages{orig.Pages[1], orig.Pages[0]}, orig.Pages[2:]...)
+		e.CoverPage = 1
+		require.NoError(t, s.AddEntry(e, 1))
+
+		buf, mime, err := s.GetThumbnail(e.SID, e.EID)
+		require.NoError(t, err)
+		require.Equal(t, "image/jpeg", mime)
+		require.Equal(t, origThumb, buf)
+	})
+
 	t.Run("thumbnails removed on deletion", func(t *testing.T) {
 		// A nil population deletes all current
 		// series and entries
@@ -444,6 +656,76 @@ func TestStore_GetThumbnail(t *testing.T) {
 
 // Series
 
+func TestStore_GetSeriesCover(t *testing.T) {
+	t.Run("first entry", func(t *testing.T) {
+		s := mustOpenStoreMem(t)
+		defer mustCloseStore(t, s)
+		lib, err := ParseLibrary("tests/lib", LibraryOptions{})
+		require.NoError(t, err)
+		require.NoError(t, s.PopulateCatalog(lib))
+
+		expected, expectedMime, err := s.GetCover(akiraSeries.SID, akiraEntries[0].EID)
+		require.NoError(t, err)
+		cover, mime, err := s.GetSeriesCover(akiraSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, expected, cover)
+		require.Equal(t, expectedMime, mime)
+
+		expected, _, err = s.GetThumbnail(akiraSeries.SID, akiraEntries[0].EID)
+		require.NoError(t, err)
+		thumb, mime, err := s.GetSeriesThumbnail(akiraSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, expected, thumb)
+		require.Equal(t, "image/jpeg", mime)
+
+		// The feeds know the cover's type without reading it
+		types, err := s.GetSeriesCoverTypes()
+		require.NoError(t, err)
+		require.Equal(t, "image/jpeg", types[centurySeries.SID])
+		require.NoError(t, s.SetEntryOverride(centurySeries.SID, centuryEntries[0].EID, "cover", "1"))
+		types, err = s.GetSeriesCoverTypes()
+		require.NoError(t, err)
+		require.Equal(t, "image/png", types[centurySeries.SID])
+		_, mime, err = s.GetSeriesCover(centurySeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, "image/png", mime)
+
+		// The entries' pages aren't read
+		_, err = s.pool.Exec(`UPDATE entries SET pages = 'invalid'`)
+		require.NoError(t, err)
+		types, err = s.GetSeriesCoverTypes()
+		require.NoError(t, err)
+		require.Equal(t, "image/png", types[centurySeries.SID])
+		require.Equal(t, "image/jpeg", types[akiraSeries.SID])
+	})
+
+	t.Run("cover file", func(t *testing.T) {
+		s := mustOpenStoreMem(t)
+		defer mustCloseStore(t, s)
+		lib, err := ParseLibrary("tests/lib-cover", LibraryOptions{})
+		require.NoError(t, err)
+		require.NoError(t, s.PopulateCatalog(lib))
+
+		data, err := os.ReadFile("tests/lib-cover/Amano/Cover.jpg")
+		require.NoError(t, err)
+		cover, mime, err := s.GetSeriesCover(amanoSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, data, cover.Bytes())
+		require.Equal(t, "image/jpeg", mime)
+
+		// The thumbnail is cached after it's generated
+		thumb, _, err := s.GetSeriesThumbnail(amanoSeries.SID)
+		require.NoError(t, err)
+		cached, _, err := s.GetSeriesThumbnail(amanoSeries.SID)
+		require.NoError(t, err)
+		require.Equal(t, thumb, cached)
+
+		var count int
+		require.NoError(t, s.pool.Get(&count, `SELECT COUNT(*) FROM series_thumbnails WHERE sid = ?`, amanoSeries.SID))
+		require.Equal(t, 1, count)
+	})
+}
+
 func TestStore_AddSeries(t *testing.T) {
 	s := mustOpenStoreMem(t)
 	defer mustCloseStore(t, s)
@@ -468,12 +750,28 @@ func TestStore_AddSeries(t *testing.T) {
 		require.Equal(t, false, missing)
 	})
 
+	t.Run("mod time in a zone without a name", func(t *testing.T) {
+		sr := Series{
+			SID:     "c",
+			Title:   "d",
+			ModTime: time.Date(2022, 8, 11, 16, 53, 23, 0, time.FixedZone("", 3600)),
+		}
+		require.NoError(t, s.AddSeries(sr, 2))
+
+		ssr, err := s.GetSeries(sr.SID)
+		require.NoError(t, err)
+		require.True(t, sr.ModTime.Equal(ssr.ModTime))
+		_, err = s.pool.Exec(`DELETE FROM series WHERE sid = ?`, sr.SID)
+		require.NoError(t, err)
+	})
+
 	t.Run("modified entry", func(t *testing.T) {
 		sr := Series{
 			SID: "a",
 			// Fields below this comment have been modified
 			Title:   "z",
 			ModTime: time.Now().Round(0), // Strip the monotonic clock reading
+			Info:    SeriesInfo{Publisher: "y", Year: 2000, Genre: "x, w"},
 		}
 		require.NoError(t, s.AddSeries(sr, 2))
 
@@ -556,13 +854,22 @@ func TestStore_GetCatalog(t *testing.T) {
 	srss, err := s.GetCatalog()
 	require.NoError(t, err)
 	require.Equal(t, srs, srss)
+
+	t.Run("same sort titles", func(t *testing.T) {
+		// Series are in the order of their positions
+		require.NoError(t, s.SetSeriesOverride("e", "sort-title", "a"))
+		require.NoError(t, s.SetSeriesOverride("c", "sort-title", "a"))
+		srss, err := s.GetCatalog()
+		require.NoError(t, err)
+		require.Equal(t, []string{"c", "e", "a"}, []string{srss[0].SID, srss[1].SID, srss[2].SID})
+	})
 }
 
 func TestStore_PopulateCatalog(t *testing.T) {
 	s := mustOpenStoreMem(t)
 	defer mustCloseStore(t, s)
 
-	lib, err := ParseLibrary("tests/lib")
+	lib, err := ParseLibrary("tests/lib", LibraryOptions{})
 	require.NoError(t, err)
 
 	t.Run("data added", func(t *testing.T) {
@@ -605,6 +912,105 @@ func TestStore_PopulateCatalog(t *testing.T) {
 	})
 }
 
+func TestStore_UpdateCatalog(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	dir := t.TempDir()
+	require.NoError(t, os.CopyFS(dir, os.DirFS("tests/lib")))
+	scan := func(t *testing.T) (CatalogChanges, map[Series][]Entry) {
+		known, err := s.KnownEntries()
+		require.NoError(t, err)
+		lib, err := ParseLibrary(dir, LibraryOptions{Known: known})
+		require.NoError(t, err)
+		c, err := s.UpdateCatalog(lib)
+		require.NoError(t, err)
+		return c, lib
+	}
+
+	c, _ := scan(t)
+	require.Equal(t, CatalogChanges{Added: 5}, c)
+	// Unchanged series aren't rewritten, so credits which
+	// are cleared behind the store's back stay cleared
+	credits := func(sid string) int {
+		var n int
+		require.NoError(t, s.pool.Get(&n, `SELECT COUNT(*) FROM series_creators WHERE sid = ?`, sid))
+		return n
+	}
+	_, err := s.pool.Exec(`DELETE FROM series_creators`)
+	require.NoError(t, err)
+	c, _ = scan(t)
+	require.Equal(t, CatalogChanges{Unchanged: 5}, c)
+	require.Zero(t, credits(akiraSeries.SID))
+
+	// Touching an archive changes it even if its contents don't
+	modTime := time.Now().Add(time.Hour).Round(time.Second)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "Akira", "Volume 01.zip"), modTime, modTime))
+	require.NoError(t, os.Remove(filepath.Join(dir, "Akira", "Volume 02.zip")))
+	data, err := os.ReadFile("tests/lib-cbt/Amano/Amano Megumi wa Suki Darake! v01.cbt")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Amano", "Amano Megumi wa Suki Darake! v02.cbt"), data, 0o644))
+	c, lib := scan(t)
+	require.Equal(t, CatalogChanges{Added: 1, Changed: 1, Removed: 1, Unchanged: 3}, c)
+	require.Equal(t, 1, credits(akiraSeries.SID))
+	require.Zero(t, credits(centurySeries.SID))
+	ctl, err := s.GetCatalog()
+	require.NoError(t, err)
+	require.Len(t, ctl, 3)
+
+	// What's stored matches a full scan
+	full, err := ParseLibrary(dir, LibraryOptions{})
+	require.NoError(t, err)
+	require.Equal(t, full, lib)
+	for sr, expected := range full {
+		entries, err := s.GetEntries(sr.SID)
+		require.NoError(t, err)
+		require.Equal(t, expected, entries)
+	}
+}
+
+func TestStore_UpdateSeries(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	lib, err := ParseLibrary("tests/lib", LibraryOptions{})
+	require.NoError(t, err)
+	require.NoError(t, s.PopulateCatalog(lib))
+
+	// Only the series at the path is updated
+	entries := lib[akiraSeries][:1]
+	c, err := s.UpdateSeries([]string{akiraSeries.Path}, map[Series][]Entry{akiraSeries: entries})
+	require.NoError(t, err)
+	require.Equal(t, CatalogChanges{Removed: 1, Unchanged: 1}, c)
+	es, err := s.GetEntries(akiraSeries.SID)
+	require.NoError(t, err)
+	require.Equal(t, entries, es)
+	ctl, err := s.GetCatalog()
+	require.NoError(t, err)
+	require.Equal(t, []Series{centurySeries, akiraSeries, amanoSeries}, ctl)
+
+	// Series which no longer exist are removed
+	c, err = s.UpdateSeries([]string{amanoSeries.Path}, nil)
+	require.NoError(t, err)
+	require.Equal(t, CatalogChanges{Removed: 1}, c)
+	ctl, err = s.GetCatalog()
+	require.NoError(t, err)
+	require.Equal(t, []Series{centurySeries, akiraSeries}, ctl)
+
+	// Entries can't claim the EIDs of entries in other series
+	renamed := lib[centurySeries][0]
+	renamed.SID = akiraSeries.SID
+	renamed.EID = Sha256("Renamed")
+	_, err = s.UpdateSeries([]string{akiraSeries.Path}, map[Series][]Entry{akiraSeries: {entries[0], renamed}})
+	require.NoError(t, err)
+	es, err = s.GetEntries(akiraSeries.SID)
+	require.NoError(t, err)
+	require.Equal(t, Sha256("Renamed"), es[1].EID)
+	es, err = s.GetEntries(centurySeries.SID)
+	require.NoError(t, err)
+	require.Len(t, es, 2)
+}
+
 // Helpers
 
 func (s *Store) GetUser(name string) (User, error) {
@@ -0,0 +1,227 @@
+package tanuki
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maruel/natural"
+)
+
+// Tags
+
+// Series are tagged with the genres found in their metadata,
+// admins can also add or remove tags from a series. Tags are
+// case-insensitive, so their ID is the hash of the lowercase
+// name, and they keep the first name they were seen with
+type Tag struct {
+	TID     string
+	Name    string
+	Count   int       // Number of series with the tag
+	ModTime time.Time // Latest mod time of its series
+}
+
+var errEmptyTag = errors.New("tag cannot be empty")
+
+func tagID(name string) string {
+	return Sha256(strings.ToLower(name))
+}
+
+// Sets the series' tags to the ones found when scanning
+// it, with the admin's edits applied on top
+func (s *Store) setSeriesTags(tx *sqlx.Tx, sid string, scanned []string) error {
+	var edits []struct {
+		TID   string
+		Name  string
+		Added bool
+	}
+	err := tx.Select(&edits, `SELECT tid, name, added FROM tag_edits WHERE sid = ? ORDER BY ROWID`, sid)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{})
+	for _, e := range edits {
+		if !e.Added {
+			seen[e.TID] = struct{}{}
+		}
+	}
+	var names []string
+	add := func(name string) {
+		if _, found := seen[tagID(name)]; !found {
+			seen[tagID(name)] = struct{}{}
+			names = append(names, name)
+		}
+	}
+	for _, name := range scanned {
+		add(name)
+	}
+	for _, e := range edits {
+		if e.Added {
+			add(e.Name)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM series_tags WHERE sid = ?`, sid); err != nil {
+		return err
+	}
+	for i, name := range names {
+		tid := tagID(name)
+		_, err := tx.Exec(`INSERT INTO tags (tid, name) VALUES (?, ?) ON CONFLICT (tid) DO NOTHING`, tid, name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO series_tags (sid, tid, position) VALUES (?, ?, ?)`, sid, tid, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) deleteUnusedTags(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`DELETE FROM tags WHERE tid NOT IN (SELECT tid FROM series_tags)`)
+	return err
+}
+
+// The series' genres are replaced by its tags,
+// so that any edits to its tags are visible
+func applyTags(sr *Series, tags []string) {
+	sr.Info.Genre = strings.Join(tags, ", ")
+}
+
+func (s *Store) getSeriesTags(q sqlx.Queryer, sid string) ([]string, error) {
+	var names []string
+	err := sqlx.Select(q, &names, `SELECT t.name FROM series_tags st JOIN tags t ON st.tid = t.tid
+		                           WHERE st.sid = ? ORDER BY st.position`, sid)
+	return names, err
+}
+
+func (s *Store) getAllSeriesTags(q sqlx.Queryer) (map[string][]string, error) {
+	var rows []struct {
+		SID  string
+		Name string
+	}
+	err := sqlx.Select(q, &rows, `SELECT st.sid, t.name FROM series_tags st JOIN tags t ON st.tid = t.tid
+		                          ORDER BY st.sid, st.position`)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string][]string)
+	for _, r := range rows {
+		m[r.SID] = append(m[r.SID], r.Name)
+	}
+	return m, nil
+}
+
+func (s *Store) editSeriesTag(sid, name string, added bool) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errEmptyTag
+	}
+
+	return s.tx(func(tx *sqlx.Tx) error {
+		// The scanned tags are needed to reapply the edits
+		var info SeriesInfo
+		err := tx.Get(&info, `SELECT info FROM series WHERE sid = ?`, sid)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("series does not exist: %s", sid)
+		} else if err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO tag_edits (sid, tid, name, added) VALUES (?, ?, ?, ?)
+		         ON CONFLICT (sid, tid) DO UPDATE SET name=excluded.name, added=excluded.added`
+		if _, err := tx.Exec(stmt, sid, tagID(name), name, added); err != nil {
+			return err
+		}
+		if err := s.setSeriesTags(tx, sid, info.Genres()); err != nil {
+			return err
+		}
+		return s.deleteUnusedTags(tx)
+	})
+}
+
+func (s *Store) AddSeriesTag(sid, name string) error {
+	return s.editSeriesTag(sid, name, true)
+}
+
+func (s *Store) RemoveSeriesTag(sid, name string) error {
+	return s.editSeriesTag(sid, name, false)
+}
+
+func (s *Store) GetTags() ([]Tag, error) {
+	var rows []struct {
+		TID     string
+		Name    string
+		ModTime time.Time
+	}
+	err := s.pool.Select(&rows, `SELECT t.tid, t.name, s.mod_time FROM tags t
+		                         JOIN series_tags st ON t.tid = st.tid
+		                         JOIN series s ON st.sid = s.sid
+		                         WHERE s.missing = 0`)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []Tag
+	index := make(map[string]int)
+	for _, r := range rows {
+		i, found := index[r.TID]
+		if !found {
+			i = len(tags)
+			index[r.TID] = i
+			tags = append(tags, Tag{TID: r.TID, Name: r.Name})
+		}
+		tags[i].Count++
+		if r.ModTime.After(tags[i].ModTime) {
+			tags[i].ModTime = r.ModTime
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return natural.Less(strings.ToLower(tags[i].Name), strings.ToLower(tags[j].Name))
+	})
+	return tags, nil
+}
+
+func (s *Store) GetTag(tid string) (Tag, error) {
+	tags, err := s.GetTags()
+	if err != nil {
+		return Tag{}, err
+	}
+	for _, t := range tags {
+		if t.TID == tid {
+			return t, nil
+		}
+	}
+	return Tag{}, fmt.Errorf("tag does not exist: %s", tid)
+}
+
+// Returns the series with the tag in catalog order
+func (s *Store) GetTaggedSeries(tid string) ([]Series, error) {
+	var sids []string
+	if err := s.pool.Select(&sids, `SELECT sid FROM series_tags WHERE tid = ?`, tid); err != nil {
+		return nil, err
+	}
+	tagged := make(map[string]struct{}, len(sids))
+	for _, sid := range sids {
+		tagged[sid] = struct{}{}
+	}
+
+	catalog, err := s.GetCatalog()
+	if err != nil {
+		return nil, err
+	}
+	var v []Series
+	for _, sr := range catalog {
+		if _, found := tagged[sr.SID]; found {
+			v = append(v, sr)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,78 @@
+package tanuki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Tags(t *testing.T) {
+	s := mustOpenStoreMem(t)
+	defer mustCloseStore(t, s)
+
+	modTime := time.Now().Round(0) // Strip the monotonic clock reading
+	a := Series{SID: "a", Title: "A", ModTime: modTime, Info: SeriesInfo{Genre: "Comedy, Romance"}}
+	b := Series{SID: "b", Title: "B", ModTime: modTime.Add(time.Hour), Info: SeriesInfo{Genre: "comedy, Drama"}}
+	lib := map[Series][]Entry{a: nil, b: nil}
+	require.NoError(t, s.PopulateCatalog(lib))
+
+	t.Run("tags from metadata", func(t *testing.T) {
+		tags, err := s.GetTags()
+		require.NoError(t, err)
+		require.Equal(t, []Tag{
+			{TID: tagID("comedy"), Name: "Comedy", Count: 2, ModTime: b.ModTime},
+			{TID: tagID("drama"), Name: "Drama", Count: 1, ModTime: b.ModTime},
+			{TID: tagID("romance"), Name: "Romance", Count: 1, ModTime: a.ModTime},
+		}, tags)
+
+		srs, err := s.GetTaggedSeries(tagID("Comedy"))
+		require.NoError(t, err)
+		require.Len(t, srs, 2)
+		require.Equal(t, "A", srs[0].Title)
+		require.Equal(t, "B", srs[1].Title)
+
+		// Tags keep the name they were first seen with
+		sr, err := s.GetSeries(b.SID)
+		require.NoError(t, err)
+		require.Equal(t, "Comedy, Drama", sr.Info.Genre)
+	})
+
+	t.Run("edited tags", func(t *testing.T) {
+		require.NoError(t, s.AddSeriesTag(a.SID, "Slice of Life"))
+		require.NoError(t, s.RemoveSeriesTag(a.SID, "ROMANCE"))
+
+		sr, err := s.GetSeries(a.SID)
+		require.NoError(t, err)
+		require.Equal(t, "Comedy, Slice of Life", sr.Info.Genre)
+
+		// Unused tags are deleted
+		_, err = s.GetTag(tagID("Romance"))
+		require.Error(t, err)
+		tag, err := s.GetTag(tagID("Slice of Life"))
+		require.NoError(t, err)
+		require.Equal(t, 1, tag.Count)
+	})
+
+	t.Run("edits preserved across rescans", func(t *testing.T) {
+		// Even if the series is missing for a scan
+		require.NoError(t, s.PopulateCatalog(map[Series][]Entry{b: nil}))
+		require.NoError(t, s.PopulateCatalog(lib))
+
+		sr, err := s.GetSeries(a.SID)
+		require.NoError(t, err)
+		require.Equal(t, "Comedy, Slice of Life", sr.Info.Genre)
+	})
+
+	t.Run("readded tag", func(t *testing.T) {
+		require.NoError(t, s.AddSeriesTag(a.SID, "Romance"))
+		ctl, err := s.GetCatalog()
+		require.NoError(t, err)
+		require.Equal(t, "Comedy, Romance, Slice of Life", ctl[0].Info.Genre)
+	})
+
+	t.Run("invalid edits", func(t *testing.T) {
+		require.ErrorIs(t, s.AddSeriesTag(a.SID, " "), errEmptyTag)
+		require.ErrorContains(t, s.AddSeriesTag("z", "Comedy"), "series does not exist")
+	})
+}
@@ -0,0 +1,58 @@
+package tanuki
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Watching
+
+// Watchers report the paths in the library which have changed,
+// so the series they're in can be rescanned without waiting for
+// the next periodic scan. The library's root is reported if the
+// watcher lost track of what changed, e.g. its queue overflowed.
+// An error is sent if the watcher stops working, at which point
+// only the periodic scans keep the catalog up to date
+type watcher interface {
+	Events() <-chan string
+	Errors() <-chan error
+	Close() error
+}
+
+var errWatchUnsupported = errors.New("filesystem watching is unsupported on this platform")
+
+// Changes are rescanned by series, i.e. by the folder in the
+// library's root they're in. Changes to files in the root itself
+// can't be, since standalone files can be grouped together, so a
+// full scan is needed
+func changedSeries(root string, changed []string) (paths []string, full bool) {
+	seen := make(map[string]struct{})
+	for _, p := range changed {
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return nil, true
+		}
+		name, _, nested := strings.Cut(filepath.ToSlash(rel), "/")
+		series := filepath.Join(root, name)
+		if !nested {
+			// Either the series itself was added or removed,
+			// or it's a file in the library's root
+			_, archive := archiveTypeOf(name)
+			stat, err := os.Stat(series)
+			if (err == nil && !stat.IsDir()) || errors.Is(err, os.ErrNotExist) {
+				if archive {
+					return nil, true
+				} else if err == nil {
+					continue
+				}
+			}
+		}
+		if _, found := seen[series]; !found {
+			seen[series] = struct{}{}
+			paths = append(paths, series)
+		}
+	}
+	return paths, false
+}
@@ -0,0 +1,179 @@
+//go:build linux
+
+package tanuki
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Inotify
+
+const inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_CLOSE_WRITE | unix.IN_MOVED_FROM |
+	unix.IN_MOVED_TO | unix.IN_ATTRIB | unix.IN_ONLYDIR
+
+// Inotify watches are per folder, so every folder in the library
+// is watched, and folders are watched as they're created. Each
+// watch counts towards the user's limit, which is commonly 8192
+// and set by fs.inotify.max_user_watches
+type inotifyWatcher struct {
+	fd   int
+	f    *os.File // Calling Fd on the file would make it blocking
+	root string
+
+	mu    sync.Mutex
+	paths map[int]string // Folders by their watch descriptor
+
+	events chan string
+	errs   chan error
+	done   chan struct{}
+}
+
+func newWatcher(root string) (watcher, error) {
+	// The file descriptor is non-blocking so reads use Go's
+	// poller, which means closing the file stops the read
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("init inotify: %w", err)
+	}
+	w := &inotifyWatcher{
+		fd:     fd,
+		f:      os.NewFile(uintptr(fd), "inotify"),
+		root:   root,
+		paths:  make(map[int]string),
+		events: make(chan string),
+		errs:   make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+	if err := w.addTree(root); err != nil {
+		w.f.Close()
+		return nil, err
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *inotifyWatcher) Errors() <-chan error {
+	return w.errs
+}
+
+func (w *inotifyWatcher) Close() error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+		close(w.done)
+	}
+	return w.f.Close()
+}
+
+func (w *inotifyWatcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Folders can be removed while they're walked
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != root && strings.HasPrefix(d.Name(), ".") {
+			return fs.SkipDir
+		}
+
+		wd, err := unix.InotifyAddWatch(w.fd, p, inotifyMask)
+		if errors.Is(err, unix.ENOSPC) {
+			return fmt.Errorf("watch %s: inotify watch limit reached: %w", p, err)
+		} else if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("watch %s: %w", p, err)
+		}
+		w.mu.Lock()
+		w.paths[wd] = p
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+func (w *inotifyWatcher) read() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			w.fail(fmt.Errorf("read inotify events: %w", err))
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(ev.Len)]), "\x00")
+			offset = nameStart + int(ev.Len)
+
+			if ev.Mask&unix.IN_Q_OVERFLOW != 0 {
+				// Events were dropped so the whole
+				// library has to be rescanned
+				slog.Warn("Inotify queue overflowed")
+				if !w.send(w.root) {
+					return
+				}
+				continue
+			}
+
+			w.mu.Lock()
+			dir, found := w.paths[int(ev.Wd)]
+			if ev.Mask&unix.IN_IGNORED != 0 {
+				delete(w.paths, int(ev.Wd))
+			}
+			w.mu.Unlock()
+			if !found || name == "" {
+				continue
+			}
+			p := filepath.Join(dir, name)
+
+			if ev.Mask&unix.IN_ISDIR != 0 && ev.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+				if err := w.addTree(p); err != nil {
+					w.fail(err)
+					return
+				}
+			}
+			if !w.send(p) {
+				return
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) send(p string) bool {
+	select {
+	case w.events <- p:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *inotifyWatcher) fail(err error) {
+	select {
+	case <-w.done:
+		// Reads fail once the watcher's closed
+	default:
+		w.errs <- err
+	}
+}
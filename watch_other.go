@@ -0,0 +1,7 @@
+//go:build !linux
+
+package tanuki
+
+func newWatcher(root string) (watcher, error) {
+	return nil, errWatchUnsupported
+}
@@ -0,0 +1,66 @@
+package tanuki
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedSeries(t *testing.T) {
+	root, err := filepath.Abs("tests/lib")
+	require.NoError(t, err)
+	akira := filepath.Join(root, "Akira")
+	amano := filepath.Join(root, "Amano")
+
+	paths, full := changedSeries(root, []string{
+		filepath.Join(akira, "Volume 01.zip"),
+		filepath.Join(amano, "author.txt"),
+		filepath.Join(akira, "Volume 02.zip"),
+		filepath.Join(root, "Removed"),
+	})
+	require.False(t, full)
+	require.Equal(t, []string{akira, amano, filepath.Join(root, "Removed")}, paths)
+
+	// Files in the library's root need a full scan, unless
+	// they can't be standalone files
+	_, full = changedSeries(root, []string{filepath.Join(root, "Removed.zip")})
+	require.True(t, full)
+	_, full = changedSeries(root, []string{root})
+	require.True(t, full)
+}
+
+func TestWatcher(t *testing.T) {
+	root := t.TempDir()
+	w, err := newWatcher(root)
+	if errors.Is(err, errWatchUnsupported) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+	defer w.Close()
+
+	next := func(t *testing.T) string {
+		select {
+		case p := <-w.Events():
+			return p
+		case err := <-w.Errors():
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for event")
+		}
+		return ""
+	}
+
+	// Folders are watched as they're created
+	series := filepath.Join(root, "Akira")
+	require.NoError(t, os.Mkdir(series, 0o755))
+	require.Equal(t, series, next(t))
+	entry := filepath.Join(series, "Volume 01.zip")
+	require.NoError(t, os.WriteFile(entry, []byte("data"), 0o644))
+	require.Equal(t, entry, next(t))
+
+	require.NoError(t, w.Close())
+}
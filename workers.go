@@ -0,0 +1,51 @@
+package tanuki
+
+import (
+	"context"
+	"sync"
+)
+
+// Workers
+
+// Calls fn on each item, and its index, using a pool of workers, the
+// results are in the same order as the items. Once the context's
+// cancelled no more items are started, the ones in progress are
+// waited for and its error is returned
+func parallel[T, R any](ctx context.Context, workers int, items []T, fn func(int, T) R) ([]R, error) {
+	workers = max(1, min(workers, len(items)))
+	results := make([]R, len(items))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = fn(i, items[i])
+			}
+		}()
+	}
+
+	var err error
+dispatch:
+	for i := range items {
+		// Cancellation takes precedence over idle workers
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
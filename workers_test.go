@@ -0,0 +1,54 @@
+package tanuki
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallel(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	t.Run("ordered", func(t *testing.T) {
+		var mu sync.Mutex
+		running, most := 0, 0
+		results, err := parallel(context.Background(), 4, items, func(_ int, i int) int {
+			mu.Lock()
+			running++
+			most = max(most, running)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return i * 2
+		})
+		require.NoError(t, err)
+		for i, r := range results {
+			require.Equal(t, i*2, r)
+		}
+		require.LessOrEqual(t, most, 4)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var started atomic.Int32
+		_, err := parallel(ctx, 4, items, func(_ int, i int) int {
+			if started.Add(1) == 10 {
+				cancel()
+			}
+			return i
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Less(t, started.Load(), int32(len(items)))
+	})
+}